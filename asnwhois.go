@@ -0,0 +1,149 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidASN is returned when the input to GetASNWhois is not an autonomous system number.
+	ErrInvalidASN = errors.New("Invalid autonomous system number")
+)
+
+// ASNInfo is the parsed aut-num record returned by an ASN WHOIS lookup.
+type ASNInfo struct {
+	ASN          uint32   `json:"asn"`
+	WhoisServer  string   `json:"whois_server,omitempty"`
+	ASName       string   `json:"as_name,omitempty"`
+	ASHandle     string   `json:"as_handle,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	Organization string   `json:"organization,omitempty"`
+	Country      string   `json:"country,omitempty"`
+	Import       []string `json:"import,omitempty"`
+	Export       []string `json:"export,omitempty"`
+	AbuseEmail   string   `json:"abuse_email,omitempty"`
+	AbusePhone   string   `json:"abuse_phone,omitempty"`
+	CreatedDate  string   `json:"created_date,omitempty"`
+	UpdatedDate  string   `json:"updated_date,omitempty"`
+}
+
+// GetASNWhois returns the WHOIS aut-num record for an autonomous system.
+// asn may be given as "AS13335" or "13335".
+func (wl *WhoisLookup) GetASNWhois(ctx context.Context, asn string) (asnInfo ASNInfo, whoisRaw string, err error) {
+	return wl.GetASNWhoisWithLocalAddr(ctx, asn, nil)
+}
+
+// GetASNWhoisWithLocalAddr returns the WHOIS aut-num record for an autonomous system.
+func (wl *WhoisLookup) GetASNWhoisWithLocalAddr(ctx context.Context, asn string, localAddr *net.TCPAddr) (asnInfo ASNInfo, whoisRaw string, err error) {
+
+	if asnInfo.ASN, err = parseASN(asn); err != nil {
+		return asnInfo, whoisRaw, err
+	}
+
+	var whoisServer string
+	if whoisRaw, whoisServer, err = wl.queryRIR(ctx, queryASN, strconv.FormatUint(uint64(asnInfo.ASN), 10), localAddr); err != nil {
+		return asnInfo, whoisRaw, err
+	}
+
+	number := asnInfo.ASN
+	asnInfo = parseASNWhois(whoisRaw)
+	asnInfo.ASN = number
+	asnInfo.WhoisServer = whoisServer
+
+	return asnInfo, whoisRaw, err
+}
+
+// parseASN accepts "AS13335", "as13335" or "13335" including asdot notation ("1.10").
+func parseASN(asn string) (number uint32, err error) {
+	value := strings.TrimSpace(asn)
+	if len(value) > 2 && strings.EqualFold(value[:2], "AS") {
+		value = value[2:]
+	}
+
+	if high, low, ok := strings.Cut(value, "."); ok {
+		h, errHigh := strconv.ParseUint(high, 10, 16)
+		l, errLow := strconv.ParseUint(low, 10, 16)
+		if errHigh != nil || errLow != nil {
+			err = fmt.Errorf("%w: %s", ErrInvalidASN, asn)
+			return number, err
+		}
+		return uint32(h<<16 | l), err
+	}
+
+	var n uint64
+	if n, err = strconv.ParseUint(value, 10, 32); err != nil {
+		err = fmt.Errorf("%w: %s", ErrInvalidASN, asn)
+		return number, err
+	}
+
+	return uint32(n), err
+}
+
+// parseASNWhois parses ARIN, RIPE style (RIPE, APNIC, AFRINIC) and LACNIC aut-num records.
+func parseASNWhois(raw string) (info ASNInfo) {
+
+	// primary is set while reading the aut-num record, later objects (role, organisation,
+	// route) carry their own dates
+	var primary bool
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			primary = false
+			continue
+		}
+
+		if m := abuseContactRegexp.FindStringSubmatch(line); m != nil {
+			info.AbuseEmail = m[1]
+			continue
+		}
+		if strings.HasPrefix(line, "%") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		switch key {
+		case "asname", "as-name":
+			setOnce(&info.ASName, value)
+		case "asnumber":
+			primary = true
+		case "ashandle", "aut-num":
+			setOnce(&info.ASHandle, value)
+			primary = true
+		case "descr":
+			setOnce(&info.Description, value)
+		case "orgname", "org-name", "owner":
+			setOnce(&info.Organization, value)
+		case "country":
+			setOnce(&info.Country, strings.ToUpper(value))
+		case "import", "mp-import":
+			info.Import = append(info.Import, value)
+		case "export", "mp-export":
+			info.Export = append(info.Export, value)
+		case "orgabuseemail", "abuse-mailbox":
+			info.AbuseEmail = value
+		case "orgabusephone":
+			info.AbusePhone = value
+		case "regdate", "created":
+			setOnce(&info.CreatedDate, value)
+		case "updated", "last-modified", "changed":
+			if primary {
+				info.UpdatedDate = value
+			}
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,95 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const testRIPEASNWhois = `% This is the RIPE Database query service.
+
+% Abuse contact for 'AS3333' is 'abuse@ripe.net'
+
+aut-num:        AS3333
+as-name:        RIPE-NCC-AS
+descr:          Reseaux IP Europeens Network Coordination Centre (RIPE NCC)
+org:            ORG-RNCC1-RIPE
+import:         from AS12859 accept ANY
+export:         to AS12859 announce AS-RIPENCC
+mp-import:      afi ipv6.unicast from AS12859 accept ANY
+created:        2002-08-15T12:41:03Z
+last-modified:  2024-01-12T10:04:33Z
+
+role:           RIPE NCC Operations
+nic-hdl:        OPS4-RIPE
+created:        2002-09-16T10:35:40Z
+last-modified:  2024-06-03T09:12:51Z
+
+route:          193.0.0.0/21
+origin:         AS3333
+created:        2008-09-10T14:27:53Z
+last-modified:  2022-05-13T10:12:44Z
+`
+
+func TestGetASNWhois(t *testing.T) {
+	queries := make(chan string, 1)
+	rir := newTestWhoisServer(t, func(query string) string {
+		queries <- query
+		return testRIPEASNWhois
+	})
+	iana := newTestWhoisServer(t, func(query string) string {
+		if query != "AS3333" {
+			return "% no match\n"
+		}
+		return "as-block:     AS3209-AS3353\nrefer:        " + rir + "\n"
+	})
+
+	whoisLookup := Setup(&Config{WhoisTLDServer: iana})
+
+	info, raw, err := whoisLookup.GetASNWhois(context.Background(), "as3333")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rirQuery := <-queries
+	if rirQuery != "AS3333" {
+		t.Errorf("unexpected RIR query: %v", rirQuery)
+	}
+	if raw != testRIPEASNWhois {
+		t.Errorf("unexpected raw: %v", raw)
+	}
+	if info.ASN != 3333 || info.ASName != "RIPE-NCC-AS" || info.ASHandle != "AS3333" || info.WhoisServer != rir {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	// The role and route objects that follow have dates of their own
+	if info.AbuseEmail != "abuse@ripe.net" || info.CreatedDate != "2002-08-15T12:41:03Z" || info.UpdatedDate != "2024-01-12T10:04:33Z" {
+		t.Errorf("unexpected abuse/dates: %+v", info)
+	}
+	if !reflect.DeepEqual(info.Import, []string{"from AS12859 accept ANY", "afi ipv6.unicast from AS12859 accept ANY"}) {
+		t.Errorf("unexpected import: %v", info.Import)
+	}
+	if !reflect.DeepEqual(info.Export, []string{"to AS12859 announce AS-RIPENCC"}) {
+		t.Errorf("unexpected export: %v", info.Export)
+	}
+}
+
+func TestParseASN(t *testing.T) {
+	tests := map[string]uint32{
+		"AS13335": 13335,
+		"13335":   13335,
+		" as1 ":   1,
+		"AS1.10":  65546,
+	}
+	for input, want := range tests {
+		got, err := parseASN(input)
+		if err != nil || got != want {
+			t.Errorf("parseASN(%v) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+
+	for _, input := range []string{"", "AS", "ASX", "4294967296", "example.com"} {
+		if _, err := parseASN(input); !errors.Is(err, ErrInvalidASN) {
+			t.Errorf("parseASN(%v) expected ErrInvalidASN, got %v", input, err)
+		}
+	}
+}
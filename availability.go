@@ -0,0 +1,178 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Availability is the outcome of an availability check.
+type Availability string
+
+const (
+	// AvailabilityUnknown means the registry could not be asked or its answer was not understood
+	AvailabilityUnknown Availability = "unknown"
+	// Available means the registry has no record of the domain
+	Available Availability = "available"
+	// Registered means the domain can not be registered, usually because it already is
+	Registered Availability = "registered"
+)
+
+// AvailabilityResult is the availability of one domain from IsAvailableMany.
+type AvailabilityResult struct {
+	Domain       string       `json:"domain"`
+	Availability Availability `json:"availability"`
+	Reason       string       `json:"reason"`
+	Err          error        `json:"-"`
+}
+
+// defaultAvailabilityConcurrency is used by IsAvailableMany when concurrency is not positive.
+const defaultAvailabilityConcurrency = 10
+
+// IsAvailable checks whether a domain is unregistered by asking only the registry WHOIS server.
+// The registry's not found answer is recognized with its ServerProfile.NotFoundPatterns, falling
+// back to the parser. Reason explains the outcome. err is only set when availability is unknown.
+func (wl *WhoisLookup) IsAvailable(ctx context.Context, domain string) (availability Availability, reason string, err error) {
+	availability = AvailabilityUnknown
+
+	var parts domainParts
+	if parts, err = wl.splitDomain(domain); err != nil {
+		return availability, "invalid domain", err
+	}
+
+	var whoisServer string
+	if whoisServer, err = wl.getWhoisServerForSuffix(ctx, parts.ETLD, nil); err != nil {
+		err = errors.Join(ErrWhoisTLD, fmt.Errorf("getTLDWhoisServer() error:%w", err))
+		return availability, "registry whois server not found", err
+	}
+
+	var whoisRaw string
+	whoisServer, err = wl.queryRegistry(ctx, parts.ETLD, whoisServer, func(server string) (err error) {
+		whoisRaw, err = wl.queryWhois(ctx, parts.RegistrableDomain, server, wl.config.DefaultTimeout, nil)
+		return err
+	})
+	if err != nil {
+		err = errors.Join(ErrWhoisRegistry, fmt.Errorf("queryWhois() server:%s error:%w", whoisServer, err))
+		return availability, "registry query failed", err
+	}
+
+	if pattern, ok := matchNotFound(whoisRaw, wl.serverProfile(whoisServer).NotFoundPatterns); ok {
+		return Available, fmt.Sprintf("%s answered %q", whoisServer, pattern), nil
+	}
+
+	var info WhoisInfo
+	info, err = wl.parse(whoisRaw, whoisServer, parts.RegistrableDomain)
+	switch {
+	case errors.Is(err, ErrDomainNotFound):
+		return Available, fmt.Sprintf("%s has no record", whoisServer), nil
+	case errors.Is(err, ErrPremiumDomain):
+		return Available, fmt.Sprintf("%s offers the domain at premium price", whoisServer), nil
+	case errors.Is(err, ErrReservedDomain):
+		return Registered, fmt.Sprintf("%s reserves the domain", whoisServer), nil
+	case errors.Is(err, ErrBlockedDomain):
+		return Registered, fmt.Sprintf("%s blocks the domain for brand protection", whoisServer), nil
+	case err != nil:
+		err = parseError(ErrParseWhoisRegistry, err)
+		return availability, "registry response not understood", err
+	case info.Domain == nil:
+		err = ErrRegistryMissingDomain
+		return availability, "registry response not understood", err
+	}
+
+	return Registered, fmt.Sprintf("%s has a record", whoisServer), nil
+}
+
+// IsAvailableMany checks many domains with up to concurrency checks at a time and returns the
+// results in input order. The registry server of each distinct suffix is looked up once up front
+// so that the checks share the TLD server cache instead of all asking IANA.
+func (wl *WhoisLookup) IsAvailableMany(ctx context.Context, domains []string, concurrency int) (results []AvailabilityResult) {
+	if concurrency <= 0 {
+		concurrency = defaultAvailabilityConcurrency
+	}
+
+	// Warm the TLD server cache once per suffix, failures are reported by the checks themselves
+	var suffixes []string
+	seen := map[string]bool{}
+	for _, domain := range domains {
+		if parts, err := wl.splitDomain(domain); err == nil && !seen[parts.ETLD] {
+			seen[parts.ETLD] = true
+			suffixes = append(suffixes, parts.ETLD)
+		}
+	}
+	forEach(ctx, len(suffixes), concurrency, func(i int) {
+		wl.getWhoisServerForSuffix(ctx, suffixes[i], nil)
+	})
+
+	results = make([]AvailabilityResult, len(domains))
+	forEach(ctx, len(domains), concurrency, func(i int) {
+		results[i].Availability, results[i].Reason, results[i].Err = wl.IsAvailable(ctx, domains[i])
+	})
+
+	for i, domain := range domains {
+		results[i].Domain = domain
+		// Checks that were never started because ctx is done
+		if results[i].Availability == "" {
+			results[i].Availability, results[i].Reason, results[i].Err = AvailabilityUnknown, "canceled", ctx.Err()
+		}
+	}
+
+	return results
+}
+
+// forEach calls fn for 0..n-1 with up to concurrency calls at a time. Once ctx is done no
+// further calls are started.
+func forEach(ctx context.Context, n, concurrency int, fn func(i int)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// matchNotFound returns the first pattern found in raw, ignoring case and the width of runs of spaces.
+// Only the first lines before any ">>>" footer are checked, so that record fields and legal notices
+// further down a real record can not match.
+func matchNotFound(raw string, patterns []string) (pattern string, ok bool) {
+	head, _, _ := strings.Cut(headLines(raw, 30), ">>>")
+	text := collapseSpaces(strings.ToLower(head))
+	for _, pattern = range patterns {
+		if pattern != "" && strings.Contains(text, collapseSpaces(strings.ToLower(pattern))) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// collapseSpaces replaces each run of spaces and tabs with a single space.
+func collapseSpaces(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' {
+			if !space {
+				b.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,112 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestIsAvailable(t *testing.T) {
+	registry := newTestWhoisServer(t, func(query string) string {
+		switch {
+		case strings.HasPrefix(query, "taken."):
+			return testReferralWhois("whois.example.net")
+		case strings.HasPrefix(query, "reserved."):
+			return "This name is reserved by the registry.\n"
+		case strings.HasPrefix(query, "free."):
+			return "Status:      FREE\n"
+		}
+		return "No match for \"" + strings.ToUpper(query) + "\".\n"
+	})
+
+	whoisLookup := Setup(nil)
+	whoisLookup.setTLDServerToCache("com", registry)
+	whoisLookup.serverProfiles["127.0.0.1"] = ServerProfile{NotFoundPatterns: []string{"status: free"}}
+
+	tests := []struct {
+		domain string
+		want   Availability
+	}{
+		{"taken.com", Registered},
+		{"reserved.com", Registered},
+		{"free.com", Available},
+		{"unregistered.com", Available},
+		{"com", AvailabilityUnknown},
+	}
+	for _, tt := range tests {
+		availability, reason, err := whoisLookup.IsAvailable(context.Background(), tt.domain)
+		if availability != tt.want {
+			t.Errorf("IsAvailable(%v) = %v (%v, %v), want %v", tt.domain, availability, reason, err, tt.want)
+		}
+		if reason == "" {
+			t.Errorf("IsAvailable(%v): expected a reason", tt.domain)
+		}
+		if (err != nil) != (tt.want == AvailabilityUnknown) {
+			t.Errorf("IsAvailable(%v): unexpected error %v", tt.domain, err)
+		}
+	}
+}
+
+func TestIsAvailableMany(t *testing.T) {
+	var ianaQueries atomic.Int32
+	var registry string
+	iana := newTestWhoisServer(t, func(query string) string {
+		ianaQueries.Add(1)
+		return "refer: " + registry + "\n"
+	})
+	registry = newTestWhoisServer(t, func(query string) string {
+		return "No match for \"" + strings.ToUpper(query) + "\".\n"
+	})
+
+	whoisLookup := Setup(&Config{WhoisTLDServer: iana, DisableBootstrap: true, RateLimit: noRateLimit})
+
+	domains := []string{"a.com", "b.com", "c.net", "d.com", "e.net", "f.com"}
+	results := whoisLookup.IsAvailableMany(context.Background(), domains, 3)
+	if len(results) != len(domains) {
+		t.Fatalf("expected %d results, got %d", len(domains), len(results))
+	}
+	for i, result := range results {
+		if result.Domain != domains[i] || result.Availability != Available || result.Err != nil {
+			t.Errorf("unexpected result %d: %+v", i, result)
+		}
+	}
+	if n := ianaQueries.Load(); n != 2 {
+		t.Errorf("expected one IANA query per TLD, got %d", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, result := range whoisLookup.IsAvailableMany(ctx, []string{"g.com"}, 1) {
+		if result.Availability != AvailabilityUnknown || !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("expected canceled result, got %+v", result)
+		}
+	}
+}
+
+func TestMatchNotFound(t *testing.T) {
+	patterns := []string{"not found"}
+	record := testReferralWhois("whois.example.net")
+
+	tests := []struct {
+		raw  string
+		want bool
+	}{
+		{"Domain   NOT FOUND\n", true},
+		// Footers after ">>>" are not part of the answer
+		{record + ">>> Last update of WHOIS database: 2024-08-14T07:01:34Z <<<\nIf a domain is not found, it may be available.\n", false},
+		// Neither are lines far down a record
+		{record + strings.Repeat("Name Server: NS.EXAMPLE.NET\n", 30) + "Remarks: not found in the blocklist\n", false},
+	}
+	for _, tt := range tests {
+		if _, ok := matchNotFound(tt.raw, patterns); ok != tt.want {
+			t.Errorf("matchNotFound(%q) = %v, want %v", tt.raw, ok, tt.want)
+		}
+	}
+
+	whoisLookup := Setup(nil)
+	if _, ok := matchNotFound("example.nl is free\n", whoisLookup.serverProfile("whois.domain-registry.nl").NotFoundPatterns); !ok {
+		t.Error("expected the .nl registry profile to recognize its free answer")
+	}
+}
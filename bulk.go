@@ -0,0 +1,182 @@
+package whois
+
+import (
+	"context"
+	"net"
+)
+
+// LookupOptions controls LookupMany.
+type LookupOptions struct {
+	// Concurrency is the maximum number of lookups in flight, 0 means 10
+	Concurrency int
+	// PerHostConcurrency is the maximum number of lookups in flight per registry WHOIS server,
+	// so one slow registry can not take every worker. Suffixes sharing a server ("com" and
+	// "net") share the limit. 0 means 2.
+	PerHostConcurrency int
+	// LocalAddr is passed to GetWhoisWithLocalAddr
+	LocalAddr *net.TCPAddr
+	// Progress is called after each result is delivered, from a single goroutine
+	Progress func(progress LookupProgress)
+}
+
+// LookupProgress reports how far LookupMany has come.
+type LookupProgress struct {
+	Done   int
+	Total  int
+	Domain string
+	Err    error
+}
+
+// LookupResult is the outcome of one domain from LookupMany.
+type LookupResult struct {
+	Domain string
+	Result Result
+	Err    error
+}
+
+const (
+	defaultLookupConcurrency        = 10
+	defaultLookupPerHostConcurrency = 2
+)
+
+// LookupMany looks up domains with GetWhoisWithLocalAddr using a bounded worker pool and sends
+// one LookupResult per distinct domain on the returned channel, which is closed once every
+// domain has a result. Inputs with the same registrable domain ("WWW.Example.com" and
+// "example.com") are looked up once, the result carries the first spelling. The registry server of each distinct suffix is looked up first,
+// pending domains are then served round robin across registry servers. Domains whose server can
+// not be found are grouped by suffix and fail in their own lookup. When ctx is done the
+// remaining domains are delivered with ctx.Err(). The channel must be drained.
+func (wl *WhoisLookup) LookupMany(ctx context.Context, domains []string, opts LookupOptions) <-chan LookupResult {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultLookupConcurrency
+	}
+	if opts.PerHostConcurrency <= 0 {
+		opts.PerHostConcurrency = defaultLookupPerHostConcurrency
+	}
+
+	// Deduplicate, remembering the suffix of each domain
+	type pending struct {
+		domain string
+		suffix string
+	}
+	var (
+		unique       []pending
+		suffixes     []string
+		seen         = map[string]bool{}
+		seenSuffixes = map[string]bool{}
+	)
+	for _, domain := range domains {
+		id, suffix := domain, ""
+		if parts, err := wl.splitDomain(domain); err == nil {
+			id, suffix = parts.RegistrableDomain, parts.ETLD
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if suffix != "" && !seenSuffixes[suffix] {
+			seenSuffixes[suffix] = true
+			suffixes = append(suffixes, suffix)
+		}
+		unique = append(unique, pending{domain: domain, suffix: suffix})
+	}
+	total := len(unique)
+
+	out := make(chan LookupResult)
+	go func() {
+		defer close(out)
+
+		// Group by registry server, or by suffix when its server is unknown
+		servers := make([]string, len(suffixes))
+		forEach(ctx, len(suffixes), opts.Concurrency, func(i int) {
+			servers[i], _ = wl.getWhoisServerForSuffix(ctx, suffixes[i], opts.LocalAddr)
+		})
+		groups := map[string]string{}
+		for i, suffix := range suffixes {
+			groups[suffix] = suffix
+			if servers[i] != "" {
+				groups[suffix] = normalizeReferral(servers[i])
+			}
+		}
+
+		var (
+			queues = map[string][]string{}
+			keys   []string
+		)
+		for _, p := range unique {
+			key := groups[p.suffix]
+			if _, ok := queues[key]; !ok {
+				keys = append(keys, key)
+			}
+			queues[key] = append(queues[key], p.domain)
+		}
+
+		type finished struct {
+			key    string
+			result LookupResult
+		}
+		var (
+			done     = make(chan finished)
+			inFlight = map[string]int{}
+			running  int
+			count    int
+			next     int
+		)
+
+		emit := func(result LookupResult) {
+			out <- result
+			count++
+			if opts.Progress != nil {
+				opts.Progress(LookupProgress{Done: count, Total: total, Domain: result.Domain, Err: result.Err})
+			}
+		}
+
+		// pick returns the next group, round robin, that has work and room under its cap
+		pick := func() (key string, ok bool) {
+			for i := range keys {
+				key = keys[(next+i)%len(keys)]
+				if len(queues[key]) > 0 && inFlight[key] < opts.PerHostConcurrency {
+					next = (next + i + 1) % len(keys)
+					return key, true
+				}
+			}
+			return "", false
+		}
+
+		for count < total {
+			for running < opts.Concurrency && ctx.Err() == nil {
+				key, ok := pick()
+				if !ok {
+					break
+				}
+				domain := queues[key][0]
+				queues[key] = queues[key][1:]
+				inFlight[key]++
+				running++
+
+				go func() {
+					result, err := wl.GetWhoisWithLocalAddr(ctx, domain, opts.LocalAddr)
+					done <- finished{key: key, result: LookupResult{Domain: domain, Result: result, Err: err}}
+				}()
+			}
+
+			// Nothing running and nothing could start, ctx is done
+			if running == 0 {
+				for _, key := range keys {
+					for _, domain := range queues[key] {
+						emit(LookupResult{Domain: domain, Err: ctx.Err()})
+					}
+					queues[key] = nil
+				}
+				break
+			}
+
+			f := <-done
+			inFlight[f.key]--
+			running--
+			emit(f.result)
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,108 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLookupMany(t *testing.T) {
+	registrar := newTestWhoisServer(t, func(query string) string {
+		return testReferralWhois("")
+	})
+	fast := newTestWhoisServer(t, func(query string) string {
+		return testReferralWhois(registrar)
+	})
+	slow := newTestWhoisServer(t, func(query string) string {
+		time.Sleep(100 * time.Millisecond)
+		return testReferralWhois(registrar)
+	})
+
+	whoisLookup := Setup(&Config{RateLimit: noRateLimit})
+	whoisLookup.setTLDServerToCache("com", slow)
+	whoisLookup.setTLDServerToCache("net", fast)
+
+	// A.COM and www.b.com query the same registrable domains as a.com and b.com
+	domains := []string{"a.com", "b.com", "c.com", "A.COM", "d.net", "e.net", "a.com", "www.b.com"}
+	var progress []LookupProgress
+	results := whoisLookup.LookupMany(context.Background(), domains, LookupOptions{
+		Concurrency:        2,
+		PerHostConcurrency: 1,
+		Progress:           func(p LookupProgress) { progress = append(progress, p) },
+	})
+
+	var order []string
+	for result := range results {
+		if result.Err != nil {
+			t.Errorf("%v: unexpected error: %v", result.Domain, result.Err)
+		}
+		order = append(order, result.Domain)
+	}
+
+	if len(order) != 5 {
+		t.Fatalf("expected duplicates to be dropped, got %v", order)
+	}
+	// The slow registry may only hold one worker, so .net finishes first
+	if order[0] != "d.net" || order[1] != "e.net" {
+		t.Errorf("expected .net results first, got %v", order)
+	}
+	if len(progress) != 5 || progress[4].Done != 5 || progress[4].Total != 5 {
+		t.Errorf("unexpected progress: %+v", progress)
+	}
+}
+
+func TestLookupMany_SharedServer(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	shared := newTestWhoisServer(t, func(query string) string {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return testReferralWhois("")
+	})
+
+	whoisLookup := Setup(&Config{RateLimit: noRateLimit})
+	// com and net share their registry server, as they do at Verisign
+	whoisLookup.setTLDServerToCache("com", shared)
+	whoisLookup.setTLDServerToCache("net", shared)
+
+	results := whoisLookup.LookupMany(context.Background(), []string{"a.com", "b.net", "c.com", "d.net"}, LookupOptions{
+		Concurrency:        4,
+		PerHostConcurrency: 1,
+	})
+	var n int
+	for range results {
+		n++
+	}
+	if n != 4 {
+		t.Errorf("expected 4 results, got %d", n)
+	}
+	if maxInFlight.Load() != 1 {
+		t.Errorf("expected one query at a time on the shared server, got %d", maxInFlight.Load())
+	}
+}
+
+func TestLookupMany_Canceled(t *testing.T) {
+	whoisLookup := Setup(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var n int
+	for result := range whoisLookup.LookupMany(ctx, []string{"a.com", "b.net", "c.org"}, LookupOptions{}) {
+		n++
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("%v: expected context.Canceled, got %v", result.Domain, result.Err)
+		}
+	}
+	if n != 3 {
+		t.Errorf("expected a result for every domain, got %d", n)
+	}
+}
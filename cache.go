@@ -0,0 +1,188 @@
+package whois
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CacheEntry is a cached GetWhoisWithLocalAddr outcome.
+type CacheEntry struct {
+	Result Result `json:"result"`
+	// NotFound marks a negative entry, the lookup failed with ErrDomainNotFound
+	NotFound bool `json:"not_found,omitempty"`
+}
+
+// Cache stores lookup results by domain, see Config.Cache. Implementations must be safe for
+// concurrent use and must not return entries older than the ttl they were set with.
+type Cache interface {
+	Get(key string) (entry CacheEntry, ok bool)
+	Set(key string, entry CacheEntry, ttl time.Duration)
+}
+
+// TLDServerCache stores the WHOIS server of each TLD beyond the in memory cache of a
+// WhoisLookup, see Config.TLDServerCache. Implementations must be safe for concurrent use.
+type TLDServerCache interface {
+	GetServer(tld string) (server string, ok bool)
+	SetServer(tld, server string, ttl time.Duration)
+}
+
+// CacheMode controls how a single lookup uses Config.Cache, see WithCacheMode.
+type CacheMode int
+
+const (
+	// CacheDefault serves cached results and caches new ones
+	CacheDefault CacheMode = iota
+	// CacheBypass neither reads nor writes the cache
+	CacheBypass
+	// CacheRefresh always queries the servers and caches the new result
+	CacheRefresh
+)
+
+type cacheModeKey struct{}
+
+// WithCacheMode returns a context that makes lookups use the cache according to mode.
+func WithCacheMode(ctx context.Context, mode CacheMode) context.Context {
+	return context.WithValue(ctx, cacheModeKey{}, mode)
+}
+
+// cacheModeFromContext returns the cache mode set with WithCacheMode.
+func cacheModeFromContext(ctx context.Context) CacheMode {
+	if mode, ok := ctx.Value(cacheModeKey{}).(CacheMode); ok {
+		return mode
+	}
+	return CacheDefault
+}
+
+// cacheKey returns the key domain is cached under, the normalized ASCII name.
+func (wl *WhoisLookup) cacheKey(domain string) (key string, ok bool) {
+	parts, err := wl.splitDomain(domain)
+	if err != nil {
+		return "", false
+	}
+	return parts.ASCII, true
+}
+
+// cachedLookup returns the cached outcome of a lookup of domain, if any.
+func (wl *WhoisLookup) cachedLookup(ctx context.Context, domain string) (result Result, ok bool, err error) {
+	if wl.config.Cache == nil || cacheModeFromContext(ctx) != CacheDefault {
+		return result, false, err
+	}
+	key, ok := wl.cacheKey(domain)
+	if !ok {
+		return result, false, err
+	}
+
+	var entry CacheEntry
+	if entry, ok = wl.config.Cache.Get(key); !ok {
+		return result, false, err
+	}
+
+	result = entry.Result
+	result.Domain = domain
+	result.FromCache = true
+	if entry.NotFound {
+		err = fmt.Errorf("%w: cached", ErrDomainNotFound)
+	}
+	return result, true, err
+}
+
+// cacheLookup caches the outcome of a lookup of domain. Only successful lookups and domains
+// not found are cached, the latter for Config.NegativeCacheTTL.
+func (wl *WhoisLookup) cacheLookup(ctx context.Context, domain string, result Result, err error) {
+	if wl.config.Cache == nil || cacheModeFromContext(ctx) == CacheBypass {
+		return
+	}
+	key, ok := wl.cacheKey(domain)
+	if !ok {
+		return
+	}
+
+	switch {
+	case err == nil:
+		wl.config.Cache.Set(key, CacheEntry{Result: result}, wl.config.CacheTTL)
+	case errors.Is(err, ErrDomainNotFound) && wl.config.NegativeCacheTTL > 0:
+		wl.config.Cache.Set(key, CacheEntry{Result: result, NotFound: true}, wl.config.NegativeCacheTTL)
+	}
+}
+
+// LRUCache is an in memory Cache holding at most a fixed number of entries, evicting the
+// least recently used one when full. Results are stored as is, so callers share the parsed
+// records of a cached Result and must not modify them.
+type LRUCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+}
+
+type lruEntry struct {
+	key     string
+	entry   CacheEntry
+	expires time.Time
+}
+
+// NewLRUCache returns an LRUCache holding up to maxEntries results, 0 means 10000.
+func NewLRUCache(maxEntries int) *LRUCache {
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	return &LRUCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// Get returns the entry for key unless it is missing or expired.
+func (c *LRUCache) Get(key string) (entry CacheEntry, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return entry, false
+	}
+	e := elem.Value.(*lruEntry)
+	if time.Now().After(e.expires) {
+		c.remove(elem)
+		return entry, false
+	}
+	c.order.MoveToFront(elem)
+	return e.entry, true
+}
+
+// Set stores entry under key for ttl.
+func (c *LRUCache) Set(key string, entry CacheEntry, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := time.Now().Add(ttl)
+	if elem, ok := c.entries[key]; ok {
+		e := elem.Value.(*lruEntry)
+		e.entry, e.expires = entry, expires
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, entry: entry, expires: expires})
+	for c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+}
+
+// Len returns the number of entries, including expired ones not yet evicted.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *LRUCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry).key)
+}
@@ -0,0 +1,118 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLRUCache(t *testing.T) {
+	cache := NewLRUCache(2)
+
+	cache.Set("a.com", CacheEntry{Result: Result{Domain: "a.com"}}, time.Hour)
+	cache.Set("b.com", CacheEntry{Result: Result{Domain: "b.com"}}, time.Hour)
+	// a.com becomes the most recently used, b.com is evicted by c.com
+	if _, ok := cache.Get("a.com"); !ok {
+		t.Fatal("expected a.com to be cached")
+	}
+	cache.Set("c.com", CacheEntry{Result: Result{Domain: "c.com"}}, time.Hour)
+
+	if _, ok := cache.Get("b.com"); ok {
+		t.Error("expected b.com to be evicted")
+	}
+	if entry, ok := cache.Get("c.com"); !ok || entry.Result.Domain != "c.com" {
+		t.Errorf("expected c.com to be cached, got %+v", entry)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", cache.Len())
+	}
+
+	cache.Set("expired.com", CacheEntry{}, -time.Second)
+	if _, ok := cache.Get("expired.com"); ok {
+		t.Error("expected expired entry to be missing")
+	}
+	// expired.com evicted a.com and was removed when read
+	if cache.Len() != 1 {
+		t.Errorf("expected expired entry to be removed, got %d entries", cache.Len())
+	}
+}
+
+func TestGetWhoisWithLocalAddr_Cache(t *testing.T) {
+	var registrarQueries, registryQueries atomic.Int32
+	registrar := newTestWhoisServer(t, func(query string) string {
+		registrarQueries.Add(1)
+		return testReferralWhois("")
+	})
+	registry := newTestWhoisServer(t, func(query string) string {
+		registryQueries.Add(1)
+		if strings.HasPrefix(query, "free.") {
+			return "No match for \"" + strings.ToUpper(query) + "\".\n"
+		}
+		return testReferralWhois(registrar)
+	})
+
+	whoisLookup := Setup(&Config{Cache: NewLRUCache(0), RateLimit: noRateLimit})
+	whoisLookup.setTLDServerToCache("com", registry)
+	ctx := context.Background()
+
+	result, err := whoisLookup.GetWhoisWithLocalAddr(ctx, "example.com", nil)
+	if err != nil || result.FromCache {
+		t.Fatalf("unexpected first lookup: %v, from cache:%v", err, result.FromCache)
+	}
+
+	result, err = whoisLookup.GetWhoisWithLocalAddr(ctx, "EXAMPLE.com", nil)
+	if err != nil || !result.FromCache || result.Domain != "EXAMPLE.com" || result.RegistrarWhois == nil {
+		t.Fatalf("expected cached result, got %v, %+v", err, result)
+	}
+	if registryQueries.Load() != 1 || registrarQueries.Load() != 1 {
+		t.Errorf("expected one query per server, got registry:%d registrar:%d", registryQueries.Load(), registrarQueries.Load())
+	}
+
+	if result, _ = whoisLookup.GetWhoisWithLocalAddr(WithCacheMode(ctx, CacheBypass), "example.com", nil); result.FromCache {
+		t.Error("expected bypass to query the servers")
+	}
+	if result, _ = whoisLookup.GetWhoisWithLocalAddr(WithCacheMode(ctx, CacheRefresh), "example.com", nil); result.FromCache {
+		t.Error("expected refresh to query the servers")
+	}
+	if registryQueries.Load() != 3 {
+		t.Errorf("expected 3 registry queries, got %d", registryQueries.Load())
+	}
+
+	// Domains not found are cached too
+	for i := 0; i < 2; i++ {
+		result, err = whoisLookup.GetWhoisWithLocalAddr(ctx, "free.com", nil)
+		if !errors.Is(err, ErrDomainNotFound) {
+			t.Fatalf("expected ErrDomainNotFound, got %v", err)
+		}
+		if result.FromCache != (i == 1) {
+			t.Errorf("lookup %d: unexpected from cache:%v", i, result.FromCache)
+		}
+	}
+	if registryQueries.Load() != 4 {
+		t.Errorf("expected 4 registry queries, got %d", registryQueries.Load())
+	}
+}
+
+func TestGetWhoisWithLocalAddr_NegativeCacheDisabled(t *testing.T) {
+	var queries atomic.Int32
+	registry := newTestWhoisServer(t, func(query string) string {
+		queries.Add(1)
+		return "No match for \"" + strings.ToUpper(query) + "\".\n"
+	})
+
+	cache := NewLRUCache(0)
+	whoisLookup := Setup(&Config{Cache: cache, NegativeCacheTTL: -1})
+	whoisLookup.setTLDServerToCache("com", registry)
+
+	for i := 0; i < 2; i++ {
+		if _, err := whoisLookup.GetWhoisWithLocalAddr(context.Background(), "free.com", nil); !errors.Is(err, ErrDomainNotFound) {
+			t.Fatalf("expected ErrDomainNotFound, got %v", err)
+		}
+	}
+	if queries.Load() != 2 || cache.Len() != 0 {
+		t.Errorf("expected no negative caching, got %d queries and %d entries", queries.Load(), cache.Len())
+	}
+}
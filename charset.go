@@ -0,0 +1,92 @@
+package whois
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// whoisResponse is a WHOIS response decoded to UTF-8.
+type whoisResponse struct {
+	Raw      string
+	Encoding string
+	// Bytes is the undecoded response, only set when Config.KeepRawBytes is on
+	Bytes []byte
+	// Truncated is set when the response was cut down to the configured limits
+	Truncated bool
+}
+
+// decodeWhois decodes body to UTF-8 using encoding, or an auto-detected character set when
+// encoding is empty or unknown. Line endings are normalized to "\n".
+func decodeWhois(body []byte, encoding string) (resp whoisResponse) {
+
+	if encoding == "" || !knownEncoding(encoding) {
+		encoding = detectEncoding(body)
+	}
+	resp.Encoding = strings.ToLower(encoding)
+
+	text := string(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")))
+	if resp.Encoding != "utf-8" {
+		if enc, err := htmlindex.Get(resp.Encoding); err == nil {
+			if decoded, err := enc.NewDecoder().Bytes(body); err == nil {
+				text = string(decoded)
+			}
+		}
+	}
+
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	resp.Raw = text
+
+	return resp
+}
+
+func knownEncoding(encoding string) bool {
+	_, err := htmlindex.Get(encoding)
+	return err == nil
+}
+
+// detectEncoding guesses the character set of a WHOIS response. ISO-2022-JP is 7 bit and
+// recognised by its escape sequences, valid UTF-8 is taken as is, EUC-JP is recognised by its
+// byte pairs and anything else is treated as Latin-1 (windows-1252).
+func detectEncoding(body []byte) string {
+	if bytes.Contains(body, []byte("\x1b$B")) || bytes.Contains(body, []byte("\x1b$@")) {
+		return "iso-2022-jp"
+	}
+	if utf8.Valid(body) {
+		return "utf-8"
+	}
+	if looksEUCJP(body) {
+		return "euc-jp"
+	}
+	return "windows-1252"
+}
+
+// looksEUCJP reports whether every non ASCII byte is part of an EUC-JP double byte
+// sequence (0xA1-0xFE pairs, or 0x8E followed by a half width katakana byte).
+func looksEUCJP(body []byte) bool {
+	pairs := 0
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c < 0x80 {
+			continue
+		}
+		if i+1 >= len(body) {
+			return false
+		}
+		next := body[i+1]
+		switch {
+		case c == 0x8e && next >= 0xa1 && next <= 0xdf:
+		case c >= 0xa1 && c <= 0xfe && next >= 0xa1 && next <= 0xfe:
+		default:
+			return false
+		}
+		pairs++
+		i++
+	}
+	return pairs > 0
+}
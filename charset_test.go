@@ -0,0 +1,54 @@
+package whois
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+func TestDecodeWhois(t *testing.T) {
+	iso2022jp, _ := japanese.ISO2022JP.NewEncoder().String("登録者名: 日本語\n")
+	eucjp, _ := japanese.EUCJP.NewEncoder().String("登録者名: 日本語\n")
+
+	tests := []struct {
+		name     string
+		body     string
+		encoding string
+		want     string
+		detected string
+	}{
+		{"utf-8", "Registrant: Müller\r\n", "", "Registrant: Müller\n", "utf-8"},
+		{"latin-1", "Registrant: M\xfcller", "", "Registrant: Müller\n", "windows-1252"},
+		{"iso-2022-jp", iso2022jp, "", "登録者名: 日本語\n", "iso-2022-jp"},
+		{"euc-jp", eucjp, "", "登録者名: 日本語\n", "euc-jp"},
+		{"profile encoding", eucjp, "EUC-JP", "登録者名: 日本語\n", "euc-jp"},
+		{"unknown profile encoding", "plain\n", "no-such-charset", "plain\n", "utf-8"},
+	}
+	for _, tt := range tests {
+		resp := decodeWhois([]byte(tt.body), tt.encoding)
+		if resp.Raw != tt.want || resp.Encoding != tt.detected {
+			t.Errorf("%s: decodeWhois() = %q (%v), want %q (%v)", tt.name, resp.Raw, resp.Encoding, tt.want, tt.detected)
+		}
+	}
+}
+
+func TestQueryServer_KeepRawBytes(t *testing.T) {
+	body := "Registrant Name: J\xfcrgen\n"
+	server := newTestWhoisServer(t, func(query string) string {
+		return body
+	})
+
+	whoisLookup := Setup(&Config{KeepRawBytes: true})
+
+	resp, err := whoisLookup.queryServer(context.Background(), queryDomain, "example.de", server, whoisLookup.config.DefaultTimeout, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Raw != "Registrant Name: Jürgen\n" || resp.Encoding != "windows-1252" {
+		t.Errorf("unexpected decoded response: %q (%v)", resp.Raw, resp.Encoding)
+	}
+	if string(resp.Bytes) != body {
+		t.Errorf("expected original bytes to be kept, got %q", resp.Bytes)
+	}
+}
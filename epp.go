@@ -0,0 +1,221 @@
+package whois
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidDSRecord is returned by ParseDSRecord for values that are not "keytag algorithm digesttype digest".
+	ErrInvalidDSRecord = errors.New("Invalid DS record")
+)
+
+// EPPStatus is an EPP domain status code (RFC 5731, RFC 3915) as used by ICANN.
+type EPPStatus string
+
+const (
+	StatusOK                       EPPStatus = "ok"
+	StatusInactive                 EPPStatus = "inactive"
+	StatusPendingCreate            EPPStatus = "pendingCreate"
+	StatusPendingDelete            EPPStatus = "pendingDelete"
+	StatusPendingRenew             EPPStatus = "pendingRenew"
+	StatusPendingRestore           EPPStatus = "pendingRestore"
+	StatusPendingTransfer          EPPStatus = "pendingTransfer"
+	StatusPendingUpdate            EPPStatus = "pendingUpdate"
+	StatusAddPeriod                EPPStatus = "addPeriod"
+	StatusAutoRenewPeriod          EPPStatus = "autoRenewPeriod"
+	StatusRenewPeriod              EPPStatus = "renewPeriod"
+	StatusTransferPeriod           EPPStatus = "transferPeriod"
+	StatusRedemptionPeriod         EPPStatus = "redemptionPeriod"
+	StatusClientHold               EPPStatus = "clientHold"
+	StatusServerHold               EPPStatus = "serverHold"
+	StatusClientDeleteProhibited   EPPStatus = "clientDeleteProhibited"
+	StatusServerDeleteProhibited   EPPStatus = "serverDeleteProhibited"
+	StatusClientRenewProhibited    EPPStatus = "clientRenewProhibited"
+	StatusServerRenewProhibited    EPPStatus = "serverRenewProhibited"
+	StatusClientTransferProhibited EPPStatus = "clientTransferProhibited"
+	StatusServerTransferProhibited EPPStatus = "serverTransferProhibited"
+	StatusClientUpdateProhibited   EPPStatus = "clientUpdateProhibited"
+	StatusServerUpdateProhibited   EPPStatus = "serverUpdateProhibited"
+)
+
+// eppStatuses indexes the known status codes by lower case code.
+var eppStatuses = map[string]EPPStatus{}
+
+func init() {
+	for _, s := range []EPPStatus{
+		StatusOK, StatusInactive, StatusPendingCreate, StatusPendingDelete, StatusPendingRenew,
+		StatusPendingRestore, StatusPendingTransfer, StatusPendingUpdate, StatusAddPeriod,
+		StatusAutoRenewPeriod, StatusRenewPeriod, StatusTransferPeriod, StatusRedemptionPeriod,
+		StatusClientHold, StatusServerHold, StatusClientDeleteProhibited, StatusServerDeleteProhibited,
+		StatusClientRenewProhibited, StatusServerRenewProhibited, StatusClientTransferProhibited,
+		StatusServerTransferProhibited, StatusClientUpdateProhibited, StatusServerUpdateProhibited,
+	} {
+		eppStatuses[strings.ToLower(string(s))] = s
+	}
+}
+
+// ParseEPPStatus recognizes a status as found in WHOIS ("clientTransferProhibited
+// https://icann.org/epp#clientTransferProhibited", "clienttransferprohibited") or RDAP
+// ("client transfer prohibited", "active"). ok is false for registry specific statuses.
+func ParseEPPStatus(value string) (status EPPStatus, ok bool) {
+	value = strings.TrimSpace(value)
+	if i := strings.Index(value, "http"); i > 0 {
+		value = strings.TrimRight(value[:i], " (")
+	}
+
+	if strings.Contains(value, " ") {
+		value = rdapStatusToEPP(value)
+	}
+	if strings.EqualFold(value, "active") {
+		return StatusOK, true
+	}
+
+	status, ok = eppStatuses[strings.ToLower(value)]
+	return status, ok
+}
+
+// EPPStatuses is the set of EPP statuses of a domain.
+type EPPStatuses []EPPStatus
+
+// parseEPPStatuses returns the recognized EPP statuses among values.
+func parseEPPStatuses(values []string) (statuses EPPStatuses) {
+	for _, value := range values {
+		if status, ok := ParseEPPStatus(value); ok && !statuses.Has(status) {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// Has reports whether status is in the set.
+func (s EPPStatuses) Has(status EPPStatus) bool {
+	for _, v := range s {
+		if v == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLocked reports whether the domain can not be transferred away, by registrar or registry lock.
+func (s EPPStatuses) IsLocked() bool {
+	return s.Has(StatusClientTransferProhibited) || s.Has(StatusServerTransferProhibited)
+}
+
+// IsPendingDelete reports whether the domain is about to be released.
+func (s EPPStatuses) IsPendingDelete() bool {
+	return s.Has(StatusPendingDelete)
+}
+
+// IsOnHold reports whether the domain is removed from the zone by the registrar or registry.
+func (s EPPStatuses) IsOnHold() bool {
+	return s.Has(StatusClientHold) || s.Has(StatusServerHold)
+}
+
+// IsRedemption reports whether the domain was deleted and can only be restored by the registrant.
+func (s EPPStatuses) IsRedemption() bool {
+	return s.Has(StatusRedemptionPeriod) || s.Has(StatusPendingRestore)
+}
+
+// DSRecord is a DNSSEC delegation signer record (RFC 4034).
+type DSRecord struct {
+	KeyTag     uint16 `json:"key_tag"`
+	Algorithm  uint8  `json:"algorithm"`
+	DigestType uint8  `json:"digest_type"`
+	Digest     string `json:"digest"`
+}
+
+// ParseDSRecord parses "370 13 2 BE74359954660069D5C63D200C39F5603827D7DD02B56F120EE9F3A86764247C".
+func ParseDSRecord(value string) (ds DSRecord, err error) {
+	fields := strings.Fields(value)
+	if len(fields) < 4 {
+		err = fmt.Errorf("%w: %s", ErrInvalidDSRecord, value)
+		return ds, err
+	}
+
+	keyTag, errKeyTag := strconv.ParseUint(fields[0], 10, 16)
+	algorithm, errAlgorithm := strconv.ParseUint(fields[1], 10, 8)
+	digestType, errDigestType := strconv.ParseUint(fields[2], 10, 8)
+	if errKeyTag != nil || errAlgorithm != nil || errDigestType != nil {
+		err = fmt.Errorf("%w: %s", ErrInvalidDSRecord, value)
+		return ds, err
+	}
+
+	ds = DSRecord{
+		KeyTag:     uint16(keyTag),
+		Algorithm:  uint8(algorithm),
+		DigestType: uint8(digestType),
+		// Long digests are sometimes split into several words
+		Digest: strings.ToUpper(strings.Join(fields[3:], "")),
+	}
+	return ds, err
+}
+
+// AbuseContact is where to report abuse of a domain, as published by its registrar.
+type AbuseContact struct {
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+}
+
+// enrichWhoisInfo fills the ICANN fields a parser left empty from the RAA 2013 lines of raw:
+// registrar IANA ID, abuse contact, inaccuracy complaint form and DS data, and derives the
+// typed EPP statuses from Domain.Status.
+func enrichWhoisInfo(info *WhoisInfo, raw string) {
+
+	var (
+		ianaID, abuseEmail, abusePhone, complaintURL string
+		dsData                                       []DSRecord
+	)
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, ">>>") {
+			break
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "registrar iana id":
+			ianaID = value
+		case "registrar abuse contact email":
+			abuseEmail = value
+		case "registrar abuse contact phone":
+			abusePhone = value
+		case "url of the icann whois inaccuracy complaint form":
+			complaintURL = value
+		case "dnssec ds data":
+			if ds, err := ParseDSRecord(value); err == nil {
+				dsData = append(dsData, ds)
+			}
+		}
+	}
+
+	if info.RegistrarIANAID == "" {
+		info.RegistrarIANAID = ianaID
+	}
+	if info.RegistrarAbuse == nil && (abuseEmail != "" || abusePhone != "") {
+		info.RegistrarAbuse = &AbuseContact{Email: abuseEmail, Phone: abusePhone}
+	}
+
+	if info.Domain == nil {
+		return
+	}
+	if info.Domain.InaccuracyComplaintURL == "" {
+		info.Domain.InaccuracyComplaintURL = complaintURL
+	}
+	if len(info.Domain.DSData) == 0 {
+		info.Domain.DSData = dsData
+	}
+	if len(info.Domain.EPPStatus) == 0 {
+		info.Domain.EPPStatus = parseEPPStatuses(info.Domain.Status)
+	}
+}
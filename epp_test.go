@@ -0,0 +1,102 @@
+package whois
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseEPPStatus(t *testing.T) {
+	tests := []struct {
+		value  string
+		status EPPStatus
+		ok     bool
+	}{
+		{"clientTransferProhibited https://icann.org/epp#clientTransferProhibited", StatusClientTransferProhibited, true},
+		{"clienttransferprohibited", StatusClientTransferProhibited, true},
+		{"serverHold (https://icann.org/epp#serverHold)", StatusServerHold, true},
+		{"redemption period", StatusRedemptionPeriod, true},
+		{"pending delete", StatusPendingDelete, true},
+		{"active", StatusOK, true},
+		{"ok", StatusOK, true},
+		{"registered", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		status, ok := ParseEPPStatus(tt.value)
+		if status != tt.status || ok != tt.ok {
+			t.Errorf("ParseEPPStatus(%q) = %q, %v, expected %q, %v", tt.value, status, ok, tt.status, tt.ok)
+		}
+	}
+}
+
+func TestEPPStatuses(t *testing.T) {
+	statuses := parseEPPStatuses([]string{"serverTransferProhibited", "client hold", "clientHold", "connect"})
+	if len(statuses) != 2 {
+		t.Fatalf("expected 2 distinct statuses, got %v", statuses)
+	}
+	if !statuses.IsLocked() || !statuses.IsOnHold() || statuses.IsPendingDelete() || statuses.IsRedemption() {
+		t.Errorf("unexpected helpers for %v", statuses)
+	}
+
+	statuses = parseEPPStatuses([]string{"pendingDelete", "pendingRestore"})
+	if statuses.IsLocked() || !statuses.IsPendingDelete() || !statuses.IsRedemption() {
+		t.Errorf("unexpected helpers for %v", statuses)
+	}
+}
+
+func TestParseDSRecord(t *testing.T) {
+	ds, err := ParseDSRecord("370 13 2 be74359954660069d5c63d200c39f560 3827d7dd02b56f120ee9f3a86764247c")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := DSRecord{KeyTag: 370, Algorithm: 13, DigestType: 2, Digest: "BE74359954660069D5C63D200C39F5603827D7DD02B56F120EE9F3A86764247C"}
+	if ds != expected {
+		t.Errorf("unexpected DS record: %+v", ds)
+	}
+
+	for _, value := range []string{"", "370 13 2", "70000 13 2 ABCD", "370 x 2 ABCD"} {
+		if _, err := ParseDSRecord(value); !errors.Is(err, ErrInvalidDSRecord) {
+			t.Errorf("ParseDSRecord(%q) expected ErrInvalidDSRecord, got %v", value, err)
+		}
+	}
+}
+
+func TestEnrichWhoisInfo(t *testing.T) {
+	raw := `Domain Name: EXAMPLE.COM
+Registrar IANA ID: 9999
+Registrar Abuse Contact Email: abuse@example-registrar.com
+Registrar Abuse Contact Phone: +1.5555550100
+Domain Status: clientTransferProhibited https://icann.org/epp#clientTransferProhibited
+DNSSEC: signedDelegation
+DNSSEC DS Data: 370 13 2 BE74359954660069D5C63D200C39F5603827D7DD02B56F120EE9F3A86764247C
+URL of the ICANN Whois Inaccuracy Complaint Form: https://www.icann.org/wicf/
+>>> Last update of WHOIS database: 2024-08-14T07:01:34Z <<<
+
+Registrar IANA ID: 1
+`
+	info := WhoisInfo{Domain: &Domain{Domain: "example.com", Status: []string{"clientTransferProhibited"}}}
+	enrichWhoisInfo(&info, raw)
+
+	if info.RegistrarIANAID != "9999" {
+		t.Errorf("unexpected registrar IANA ID: %v", info.RegistrarIANAID)
+	}
+	if info.RegistrarAbuse == nil || info.RegistrarAbuse.Email != "abuse@example-registrar.com" || info.RegistrarAbuse.Phone != "+1.5555550100" {
+		t.Errorf("unexpected registrar abuse contact: %+v", info.RegistrarAbuse)
+	}
+	if info.Domain.InaccuracyComplaintURL != "https://www.icann.org/wicf/" {
+		t.Errorf("unexpected inaccuracy complaint URL: %v", info.Domain.InaccuracyComplaintURL)
+	}
+	if len(info.Domain.DSData) != 1 || info.Domain.DSData[0].KeyTag != 370 {
+		t.Errorf("unexpected DS data: %+v", info.Domain.DSData)
+	}
+	if !info.Domain.EPPStatus.IsLocked() {
+		t.Errorf("unexpected EPP status: %v", info.Domain.EPPStatus)
+	}
+
+	// Values from the parser are kept
+	info = WhoisInfo{RegistrarIANAID: "42", Domain: &Domain{}}
+	enrichWhoisInfo(&info, raw)
+	if info.RegistrarIANAID != "42" {
+		t.Errorf("expected parser registrar IANA ID to be kept, got %v", info.RegistrarIANAID)
+	}
+}
@@ -0,0 +1,187 @@
+package whois
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileCache is a Cache and TLDServerCache that keeps each entry in its own gzip compressed
+// JSON file below a directory, so that cached results survive restarts. Files are replaced
+// atomically, any number of WhoisLookup instances and FileCache values, in one or several
+// processes, can share a directory. Expired entries are removed when read and by Compact.
+// Errors reading or writing files are treated as cache misses.
+type FileCache struct {
+	dir string
+}
+
+// fileCacheEntry is the content of one cache file.
+type fileCacheEntry struct {
+	Key     string          `json:"key"`
+	Expires time.Time       `json:"expires"`
+	Value   json.RawMessage `json:"value"`
+}
+
+const (
+	fileCacheResults = "results"
+	fileCacheServers = "servers"
+	fileCacheExt     = ".json.gz"
+	// fileCacheTempAge is how old an unfinished temporary file must be before Compact removes it
+	fileCacheTempAge = time.Hour
+)
+
+// NewFileCache returns a FileCache storing its entries below dir, which is created if needed.
+func NewFileCache(dir string) (cache *FileCache, err error) {
+	for _, kind := range []string{fileCacheResults, fileCacheServers} {
+		if err = os.MkdirAll(filepath.Join(dir, kind), 0o755); err != nil {
+			err = fmt.Errorf("os.MkdirAll() error:%w", err)
+			return nil, err
+		}
+	}
+	return &FileCache{dir: dir}, err
+}
+
+// Get returns the lookup result cached for key.
+func (c *FileCache) Get(key string) (entry CacheEntry, ok bool) {
+	ok = c.read(fileCacheResults, key, &entry)
+	return entry, ok
+}
+
+// Set caches a lookup result under key for ttl.
+func (c *FileCache) Set(key string, entry CacheEntry, ttl time.Duration) {
+	c.write(fileCacheResults, key, entry, ttl)
+}
+
+// GetServer returns the WHOIS server cached for tld.
+func (c *FileCache) GetServer(tld string) (server string, ok bool) {
+	ok = c.read(fileCacheServers, tld, &server)
+	return server, ok
+}
+
+// SetServer caches the WHOIS server of tld for ttl.
+func (c *FileCache) SetServer(tld, server string, ttl time.Duration) {
+	c.write(fileCacheServers, tld, server, ttl)
+}
+
+// Compact removes expired and unreadable entries and temporary files left behind by
+// interrupted writes. Call it periodically for long lived caches.
+func (c *FileCache) Compact() (removed int, err error) {
+	now := time.Now()
+	err = filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			// Removed by another process meanwhile
+			if errors.Is(walkErr, fs.ErrNotExist) {
+				return nil
+			}
+			return walkErr
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		var remove bool
+		switch {
+		case strings.HasSuffix(path, fileCacheExt):
+			var entry fileCacheEntry
+			remove = readFileCacheEntry(path, &entry) != nil || now.After(entry.Expires)
+		case strings.HasPrefix(d.Name(), ".tmp"):
+			info, infoErr := d.Info()
+			remove = infoErr == nil && now.Sub(info.ModTime()) > fileCacheTempAge
+		}
+		if remove && os.Remove(path) == nil {
+			removed++
+		}
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("filepath.WalkDir() error:%w", err)
+	}
+	return removed, err
+}
+
+// path returns the file of key, spread over 256 sub directories.
+func (c *FileCache) path(kind, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, kind, name[:2], name+fileCacheExt)
+}
+
+// read decodes the entry of key into value, removing it when it is expired or unreadable.
+func (c *FileCache) read(kind, key string, value interface{}) (ok bool) {
+	path := c.path(kind, key)
+
+	var entry fileCacheEntry
+	if err := readFileCacheEntry(path, &entry); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			os.Remove(path)
+		}
+		return false
+	}
+	if entry.Key != key {
+		return false
+	}
+	if time.Now().After(entry.Expires) {
+		os.Remove(path)
+		return false
+	}
+
+	return json.Unmarshal(entry.Value, value) == nil
+}
+
+// write stores value under key. The file is written to a temporary name and renamed into
+// place, readers never see a partial entry.
+func (c *FileCache) write(kind, key string, value interface{}, ttl time.Duration) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	path := c.path(kind, key)
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp")
+	if err != nil {
+		return
+	}
+
+	zw := gzip.NewWriter(tmp)
+	err = json.NewEncoder(zw).Encode(fileCacheEntry{Key: key, Expires: time.Now().Add(ttl), Value: raw})
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// readFileCacheEntry decodes the cache file at path.
+func readFileCacheEntry(path string, entry *fileCacheEntry) (err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	return json.NewDecoder(zr).Decode(entry)
+}
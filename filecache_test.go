@@ -0,0 +1,145 @@
+package whois
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewFileCache(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	created := time.Date(1995, 8, 14, 4, 0, 0, 0, time.UTC)
+	entry := CacheEntry{Result: Result{
+		Domain:        "example.com",
+		RegistryWhois: &WhoisInfo{Domain: &Domain{Domain: "example.com", CreatedDateInTime: &created}},
+	}}
+	cache.Set("example.com", entry, time.Hour)
+	cache.Set("free.com", CacheEntry{NotFound: true}, time.Hour)
+	cache.Set("expired.com", entry, -time.Second)
+	cache.SetServer("com", "whois.verisign-grs.com", time.Hour)
+
+	// A new instance sees the entries of the first one
+	reopened, err := NewFileCache(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, ok := reopened.Get("example.com")
+	if !ok || got.Result.RegistryWhois == nil || !got.Result.RegistryWhois.Domain.CreatedDateInTime.Equal(created) {
+		t.Errorf("unexpected entry: %+v, %v", got, ok)
+	}
+	if got, ok = reopened.Get("free.com"); !ok || !got.NotFound {
+		t.Errorf("expected negative entry, got %+v, %v", got, ok)
+	}
+	if _, ok = reopened.Get("missing.com"); ok {
+		t.Error("expected missing entry")
+	}
+	if server, ok := reopened.GetServer("com"); !ok || server != "whois.verisign-grs.com" {
+		t.Errorf("unexpected server: %v, %v", server, ok)
+	}
+	// Results and servers do not share keys
+	if _, ok = reopened.GetServer("example.com"); ok {
+		t.Error("expected no server for a result key")
+	}
+
+	// A corrupt entry is a miss and is removed
+	corrupt := cache.path(fileCacheResults, "corrupt.com")
+	os.MkdirAll(filepath.Dir(corrupt), 0o755)
+	os.WriteFile(corrupt, []byte("not gzip"), 0o644)
+	if _, ok = cache.Get("corrupt.com"); ok {
+		t.Error("expected corrupt entry to be a miss")
+	}
+	if _, err = os.Stat(corrupt); !os.IsNotExist(err) {
+		t.Errorf("expected corrupt entry to be removed, got %v", err)
+	}
+
+	removed, err := cache.Compact()
+	if err != nil || removed != 1 {
+		t.Errorf("expected the expired entry to be compacted, got %d, %v", removed, err)
+	}
+	if _, ok = cache.Get("example.com"); !ok {
+		t.Error("expected live entry to survive compaction")
+	}
+}
+
+func TestFileCache_Concurrent(t *testing.T) {
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		cache, err := NewFileCache(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				key := fmt.Sprintf("domain%d.com", j%5)
+				cache.Set(key, CacheEntry{Result: Result{Domain: key}}, time.Hour)
+				// A reader sees the previous or the new file, never a partial one
+				if entry, ok := cache.Get(key); !ok || entry.Result.Domain != key {
+					t.Errorf("unexpected entry for %v: %+v, %v", key, entry, ok)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestFileCache_TLDServerCache(t *testing.T) {
+	var ianaQueries atomic.Int32
+	iana := newTestWhoisServer(t, func(query string) string {
+		ianaQueries.Add(1)
+		return "whois: whois.example-registry.test\n"
+	})
+
+	cache, err := NewFileCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		// Each WhoisLookup starts with an empty in memory cache, as after a restart
+		whoisLookup := Setup(&Config{WhoisTLDServer: iana, TLDServerCache: cache})
+		server, err := whoisLookup.GetTLDWhoisServer(context.Background(), "test")
+		if err != nil || server != "whois.example-registry.test" {
+			t.Fatalf("unexpected server: %v, %v", server, err)
+		}
+	}
+	if ianaQueries.Load() != 1 {
+		t.Errorf("expected one IANA query, got %d", ianaQueries.Load())
+	}
+
+	// A shared cache hit is kept in memory, the file is read once
+	counting := &countingTLDServerCache{TLDServerCache: cache}
+	whoisLookup := Setup(&Config{WhoisTLDServer: iana, TLDServerCache: counting})
+	for i := 0; i < 3; i++ {
+		if server, err := whoisLookup.GetTLDWhoisServer(context.Background(), "test"); err != nil || server != "whois.example-registry.test" {
+			t.Fatalf("unexpected server: %v, %v", server, err)
+		}
+	}
+	if counting.gets.Load() != 1 {
+		t.Errorf("expected one shared cache read, got %d", counting.gets.Load())
+	}
+}
+
+// countingTLDServerCache counts the reads of a TLDServerCache.
+type countingTLDServerCache struct {
+	TLDServerCache
+	gets atomic.Int32
+}
+
+func (c *countingTLDServerCache) GetServer(tld string) (server string, ok bool) {
+	c.gets.Add(1)
+	return c.TLDServerCache.GetServer(tld)
+}
@@ -2,10 +2,10 @@ module github.com/chrispassas/whois
 
 go 1.24.1
 
-require github.com/likexian/whois-parser v1.24.20
-
 require (
-	github.com/likexian/gokit v0.25.15 // indirect
-	golang.org/x/net v0.27.0 // indirect
-	golang.org/x/text v0.16.0 // indirect
+	github.com/likexian/whois-parser v1.24.20
+	golang.org/x/net v0.27.0
+	golang.org/x/text v0.16.0
 )
+
+require github.com/likexian/gokit v0.25.15 // indirect
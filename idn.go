@@ -0,0 +1,60 @@
+package whois
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var (
+	// ErrInvalidDomain is returned (wrapped in an *InvalidDomainError) when a domain fails validation.
+	ErrInvalidDomain = errors.New("Invalid domain name")
+)
+
+// InvalidDomainError reports why a domain was rejected. It matches ErrInvalidDomain with errors.Is.
+type InvalidDomainError struct {
+	Domain string
+	Reason string
+}
+
+func (e *InvalidDomainError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrInvalidDomain, e.Domain, e.Reason)
+}
+
+func (e *InvalidDomainError) Unwrap() error {
+	return ErrInvalidDomain
+}
+
+// idnaProfile maps input the way a resolver would (UTS-46 lower casing and width folding),
+// validates labels against IDNA2008 and enforces DNS length limits.
+var idnaProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.Transitional(false),
+	idna.VerifyDNSLength(true),
+	idna.StrictDomainName(true),
+)
+
+// normalizeDomain converts Unicode or A-label input to its A-label (ASCII) and U-label (Unicode) forms.
+func normalizeDomain(domain string) (ascii, unicode string, err error) {
+
+	name := strings.TrimSuffix(strings.TrimSpace(domain), ".")
+	if name == "" {
+		err = &InvalidDomainError{Domain: domain, Reason: "empty domain"}
+		return ascii, unicode, err
+	}
+
+	if ascii, err = idnaProfile.ToASCII(name); err != nil {
+		err = &InvalidDomainError{Domain: domain, Reason: strings.TrimPrefix(err.Error(), "idna: ")}
+		return ascii, unicode, err
+	}
+
+	if unicode, err = idnaProfile.ToUnicode(ascii); err != nil {
+		err = &InvalidDomainError{Domain: domain, Reason: strings.TrimPrefix(err.Error(), "idna: ")}
+		return ascii, unicode, err
+	}
+
+	return ascii, unicode, err
+}
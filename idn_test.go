@@ -0,0 +1,47 @@
+package whois
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSplitDomain_IDN(t *testing.T) {
+	whoisLookup := Setup(nil)
+
+	tests := []struct {
+		domain      string
+		registrable string
+		unicode     string
+	}{
+		{"bücher.de", "xn--bcher-kva.de", "bücher.de"},
+		{"xn--bcher-kva.de", "xn--bcher-kva.de", "bücher.de"},
+		{"WWW.BÜCHER.DE", "xn--bcher-kva.de", "www.bücher.de"},
+		{"例え.テスト", "xn--r8jz45g.xn--zckzah", "例え.テスト"},
+	}
+	for _, tt := range tests {
+		parts, err := whoisLookup.splitDomain(tt.domain)
+		if err != nil {
+			t.Errorf("splitDomain(%v) unexpected error: %v", tt.domain, err)
+			continue
+		}
+		if parts.RegistrableDomain != tt.registrable || parts.Unicode != tt.unicode {
+			t.Errorf("splitDomain(%v) = %+v, want %v / %v", tt.domain, parts, tt.registrable, tt.unicode)
+		}
+	}
+}
+
+func TestNormalizeDomain_Invalid(t *testing.T) {
+	for _, domain := range []string{"", "a_b.com", "-abc.com", "xn--zz.com", strings.Repeat("a", 64) + ".com"} {
+		_, _, err := normalizeDomain(domain)
+
+		var invalid *InvalidDomainError
+		if !errors.As(err, &invalid) || !errors.Is(err, ErrInvalidDomain) {
+			t.Errorf("normalizeDomain(%q) expected *InvalidDomainError, got %v", domain, err)
+			continue
+		}
+		if invalid.Reason == "" {
+			t.Errorf("normalizeDomain(%q) expected a reason", domain)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Command gentldservers writes the IANA root zone WHOIS server table bundled with the whois
+// package. It reads the list of TLDs from IANA and asks whois.iana.org for the server of each.
+//
+//	go generate github.com/chrispassas/whois
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chrispassas/whois"
+)
+
+const tldListURL = "https://data.iana.org/TLD/tlds-alpha-by-domain.txt"
+
+func main() {
+	output := flag.String("o", "tldservers.txt", "output file")
+	concurrency := flag.Int("c", 4, "concurrent IANA queries")
+	flag.Parse()
+
+	if err := run(*output, *concurrency); err != nil {
+		log.Fatalf("gentldservers: %v", err)
+	}
+}
+
+func run(output string, concurrency int) (err error) {
+
+	ctx := context.Background()
+
+	var tlds []string
+	if tlds, err = fetchTLDs(ctx); err != nil {
+		err = fmt.Errorf("fetchTLDs() error:%w", err)
+		return err
+	}
+
+	// Always ask IANA, never the snapshot being replaced
+	wl := whois.Setup(&whois.Config{DisableBootstrap: true})
+
+	var (
+		mu      sync.Mutex
+		lines   []string
+		failed  int
+		wg      sync.WaitGroup
+		pending = make(chan string)
+	)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for tld := range pending {
+				server, err := wl.GetTLDWhoisServer(ctx, tld)
+				mu.Lock()
+				switch {
+				case err == nil:
+					lines = append(lines, tld+" "+server)
+				case !errors.Is(err, whois.ErrWhoisServerNotFound):
+					// A TLD without a WHOIS server is expected, anything else is not
+					log.Printf("tld:%s error:%v", tld, err)
+					failed++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, tld := range tlds {
+		pending <- tld
+	}
+	close(pending)
+	wg.Wait()
+
+	if failed > 0 {
+		err = fmt.Errorf("%d TLDs failed, the snapshot was not written", failed)
+		return err
+	}
+	slices.Sort(lines)
+
+	var b strings.Builder
+	b.WriteString("# IANA root zone WHOIS servers, one \"tld server\" per line. Regenerate with go generate.\n")
+	fmt.Fprintf(&b, "# generated: %s\n", time.Now().UTC().Format(time.RFC3339))
+	for _, line := range lines {
+		b.WriteString(line + "\n")
+	}
+
+	if err = os.WriteFile(output, []byte(b.String()), 0o644); err != nil {
+		err = fmt.Errorf("os.WriteFile() error:%w", err)
+	}
+	return err
+}
+
+// fetchTLDs returns the lower case TLDs of the root zone in A-label form.
+func fetchTLDs(ctx context.Context) (tlds []string, err error) {
+
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, tldListURL, nil); err != nil {
+		return tlds, err
+	}
+
+	var resp *http.Response
+	if resp, err = http.DefaultClient.Do(req); err != nil {
+		return tlds, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("unexpected status:%s", resp.Status)
+		return tlds, err
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tlds = append(tlds, strings.ToLower(line))
+	}
+	err = scanner.Err()
+
+	return tlds, err
+}
@@ -0,0 +1,302 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrInvalidIP is returned when the input to GetIPWhois is not an IPv4 or IPv6 address.
+	ErrInvalidIP = errors.New("Invalid IP address")
+	// ErrRIRReferral is returned when IANA does not refer an IP address or ASN to a RIR.
+	ErrRIRReferral = errors.New("IANA whois response missing refer server")
+)
+
+// maxRIRReferrals bounds how many RIR to RIR referrals are followed (e.g. ARIN legacy space held by RIPE).
+const maxRIRReferrals = 3
+
+// IPWhoisInfo is the parsed network record returned by an IP address WHOIS lookup.
+type IPWhoisInfo struct {
+	IP           string   `json:"ip"`
+	WhoisServer  string   `json:"whois_server,omitempty"`
+	Range        string   `json:"range,omitempty"`
+	CIDR         []string `json:"cidr,omitempty"`
+	NetName      string   `json:"net_name,omitempty"`
+	NetHandle    string   `json:"net_handle,omitempty"`
+	NetType      string   `json:"net_type,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	Organization string   `json:"organization,omitempty"`
+	Country      string   `json:"country,omitempty"`
+	AbuseEmail   string   `json:"abuse_email,omitempty"`
+	AbusePhone   string   `json:"abuse_phone,omitempty"`
+	CreatedDate  string   `json:"created_date,omitempty"`
+	UpdatedDate  string   `json:"updated_date,omitempty"`
+}
+
+// GetIPWhois returns the WHOIS network record for an IPv4 or IPv6 address.
+// The lookup starts at the IANA WHOIS server and follows the "refer:" line to the responsible RIR.
+func (wl *WhoisLookup) GetIPWhois(ctx context.Context, ip string) (ipInfo IPWhoisInfo, whoisRaw string, err error) {
+	return wl.GetIPWhoisWithLocalAddr(ctx, ip, nil)
+}
+
+// GetIPWhoisWithLocalAddr returns the WHOIS network record for an IPv4 or IPv6 address.
+func (wl *WhoisLookup) GetIPWhoisWithLocalAddr(ctx context.Context, ip string, localAddr *net.TCPAddr) (ipInfo IPWhoisInfo, whoisRaw string, err error) {
+
+	var addr netip.Addr
+	if addr, err = netip.ParseAddr(strings.TrimSpace(ip)); err != nil {
+		err = fmt.Errorf("%w: %s", ErrInvalidIP, ip)
+		return ipInfo, whoisRaw, err
+	}
+	addr = addr.Unmap()
+	ip = addr.String()
+
+	var whoisServer string
+	if whoisRaw, whoisServer, err = wl.queryRIR(ctx, queryIP, ip, localAddr); err != nil {
+		return ipInfo, whoisRaw, err
+	}
+
+	ipInfo = parseIPWhois(whoisRaw)
+	ipInfo.IP = ip
+	ipInfo.WhoisServer = whoisServer
+
+	if len(ipInfo.CIDR) == 0 && ipInfo.Range != "" {
+		ipInfo.CIDR = rangeToCIDR(ipInfo.Range)
+	}
+
+	return ipInfo, whoisRaw, err
+}
+
+// queryRIR asks the IANA WHOIS server which RIR is responsible for object, follows the
+// "refer:" line and any RIR to RIR referrals, and returns the final response and server.
+// Each server's profile formats the query. ASNs are passed as a bare number.
+func (wl *WhoisLookup) queryRIR(ctx context.Context, kind queryKind, object string, localAddr *net.TCPAddr) (whoisRaw, whoisServer string, err error) {
+
+	ianaQuery := object
+	if kind == queryASN {
+		ianaQuery = "AS" + object
+	}
+	if wl.config.Offline {
+		err = fmt.Errorf("%w: %s", ErrOffline, ianaQuery)
+		return whoisRaw, whoisServer, err
+	}
+
+	var ianaRaw string
+	if _, err = wl.retry(ctx, StageIANA, wl.config.WhoisTLDServer, func() (queryErr error) {
+		ianaRaw, queryErr = wl.queryWhoisAddr(ctx, ianaQuery, wl.config.WhoisTLDServer, wl.config.DefaultTimeout, localAddr)
+		return queryErr
+	}); err != nil {
+		err = fmt.Errorf("queryWhoisAddr() server:%s error:%w", wl.config.WhoisTLDServer, err)
+		return whoisRaw, whoisServer, err
+	}
+
+	next := parseReferServer(ianaRaw)
+	if next == "" {
+		err = fmt.Errorf("%w for: %s", ErrRIRReferral, ianaQuery)
+		return ianaRaw, whoisServer, err
+	}
+
+	visited := map[string]bool{}
+	for i := 0; i <= maxRIRReferrals && next != "" && !visited[next]; i++ {
+		visited[next] = true
+		whoisServer = next
+
+		var resp whoisResponse
+		if _, err = wl.retry(ctx, StageRegistry, whoisServer, func() (queryErr error) {
+			resp, queryErr = wl.queryServer(ctx, kind, object, whoisServer, wl.config.DefaultTimeout, localAddr)
+			return queryErr
+		}); err != nil {
+			err = fmt.Errorf("queryServer() server:%s error:%w", whoisServer, err)
+			return whoisRaw, whoisServer, err
+		}
+		whoisRaw = resp.Raw
+
+		next = parseReferralServer(whoisRaw)
+	}
+
+	return whoisRaw, whoisServer, err
+}
+
+// parseReferServer returns the server from an IANA "refer:" (or "whois:") line.
+func parseReferServer(raw string) string {
+	for _, line := range strings.Split(raw, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "refer", "whois":
+			if value = strings.TrimSpace(value); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// parseReferralServer returns the WHOIS server from an ARIN "ReferralServer: whois://..." line.
+// rwhois and other schemes are ignored.
+func parseReferralServer(raw string) string {
+	for _, line := range strings.Split(raw, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "ReferralServer") {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if server, ok := strings.CutPrefix(value, "whois://"); ok {
+			return strings.TrimSuffix(server, "/")
+		}
+	}
+	return ""
+}
+
+var abuseContactRegexp = regexp.MustCompile(`(?i)abuse contact for .* is '([^']+)'`)
+
+// parseIPWhois parses ARIN, RIPE style (RIPE, APNIC, AFRINIC) and LACNIC network records.
+// When several network records are returned the last, most specific one wins.
+func parseIPWhois(raw string) (info IPWhoisInfo) {
+
+	// primary is set while reading the network record, later objects (role, organisation,
+	// route) carry their own dates
+	var primary bool
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			primary = false
+			continue
+		}
+
+		if m := abuseContactRegexp.FindStringSubmatch(line); m != nil {
+			info.AbuseEmail = m[1]
+			continue
+		}
+		if strings.HasPrefix(line, "%") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		switch key {
+		case "netrange", "inetnum", "inet6num":
+			// A new network record starts, drop fields of the less specific one
+			info = IPWhoisInfo{AbuseEmail: info.AbuseEmail, AbusePhone: info.AbusePhone}
+			primary = true
+			if strings.Contains(value, "/") {
+				info.CIDR = append(info.CIDR, normalizeCIDR(value))
+			} else {
+				info.Range = value
+			}
+		case "cidr":
+			info.CIDR = nil
+			for _, cidr := range strings.Split(value, ",") {
+				info.CIDR = append(info.CIDR, strings.TrimSpace(cidr))
+			}
+		case "netname":
+			info.NetName = value
+		case "nethandle":
+			info.NetHandle = value
+		case "nettype", "status":
+			setOnce(&info.NetType, value)
+		case "descr":
+			setOnce(&info.Description, value)
+		case "organization", "orgname", "org-name", "owner":
+			setOnce(&info.Organization, value)
+		case "country":
+			setOnce(&info.Country, strings.ToUpper(value))
+		case "orgabuseemail", "abuse-mailbox":
+			info.AbuseEmail = value
+		case "orgabusephone":
+			info.AbusePhone = value
+		case "regdate", "created":
+			setOnce(&info.CreatedDate, value)
+		case "updated", "last-modified", "changed":
+			if primary {
+				info.UpdatedDate = value
+			}
+		}
+	}
+
+	if info.Range == "" && len(info.CIDR) > 0 {
+		if prefix, err := netip.ParsePrefix(info.CIDR[0]); err == nil {
+			info.Range = prefix.Masked().Addr().String() + " - " + lastAddr(prefix).String()
+		}
+	}
+
+	return info
+}
+
+func setOnce(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+// normalizeCIDR expands LACNIC's abbreviated IPv4 prefixes ("200.3.12/22").
+func normalizeCIDR(value string) string {
+	addr, bits, ok := strings.Cut(value, "/")
+	if !ok || strings.Contains(addr, ":") {
+		return value
+	}
+	for strings.Count(addr, ".") < 3 {
+		addr += ".0"
+	}
+	return addr + "/" + bits
+}
+
+// rangeToCIDR converts "start - end" into the minimal list of covering prefixes.
+func rangeToCIDR(r string) (cidrs []string) {
+	startStr, endStr, ok := strings.Cut(r, "-")
+	if !ok {
+		return cidrs
+	}
+	start, err := netip.ParseAddr(strings.TrimSpace(startStr))
+	if err != nil {
+		return cidrs
+	}
+	end, err := netip.ParseAddr(strings.TrimSpace(endStr))
+	if err != nil || start.Is4() != end.Is4() || end.Less(start) {
+		return cidrs
+	}
+
+	for {
+		// Grow the prefix while it stays aligned on start and inside the range
+		bits := start.BitLen()
+		for bits > 0 {
+			prefix := netip.PrefixFrom(start, bits-1).Masked()
+			if prefix.Addr() != start || end.Less(lastAddr(prefix)) {
+				break
+			}
+			bits--
+		}
+		prefix := netip.PrefixFrom(start, bits)
+		cidrs = append(cidrs, prefix.String())
+
+		last := lastAddr(prefix)
+		if last == end || !last.Next().IsValid() {
+			return cidrs
+		}
+		start = last.Next()
+	}
+}
+
+// lastAddr returns the last address inside prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Masked().Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - uint(i%8))
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
@@ -0,0 +1,129 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const testARINWhois = `#
+# ARIN WHOIS data and services are subject to the Terms of Use
+#
+
+NetRange:       8.0.0.0 - 8.127.255.255
+CIDR:           8.0.0.0/9
+NetName:        LVLT-ORG-8-8
+NetHandle:      NET-8-0-0-0-1
+NetType:        Direct Allocation
+Organization:   Level 3 Parent, LLC (LPL-141)
+RegDate:        1992-12-01
+
+NetRange:       8.8.8.0 - 8.8.8.255
+CIDR:           8.8.8.0/24
+NetName:        GOGL
+NetHandle:      NET-8-8-8-0-2
+NetType:        Direct Allocation
+Organization:   Google LLC (GOGL)
+RegDate:        2023-12-28
+Updated:        2023-12-28
+
+OrgName:        Google LLC
+Country:        US
+OrgAbusePhone:  +1-650-253-0000
+OrgAbuseEmail:  network-abuse@google.com
+`
+
+const testRIPEWhois = `% This is the RIPE Database query service.
+
+% Abuse contact for '193.0.0.0 - 193.0.7.255' is 'abuse@ripe.net'
+
+inetnum:        193.0.0.0 - 193.0.7.255
+netname:        RIPE-NCC
+descr:          RIPE Network Coordination Centre
+country:        NL
+status:         ASSIGNED PA
+created:        2003-03-17T12:15:57Z
+last-modified:  2017-12-04T14:42:31Z
+
+role:           RIPE NCC Operations
+address:        Stationsplein 11
+nic-hdl:        OPS4-RIPE
+created:        2002-09-16T10:35:40Z
+last-modified:  2024-01-11T10:43:18Z
+
+route:          193.0.0.0/21
+descr:          RIPE-NCC
+origin:         AS3333
+created:        2008-09-10T14:27:53Z
+last-modified:  2022-05-13T10:12:44Z
+`
+
+func TestGetIPWhois(t *testing.T) {
+	rir := newTestWhoisServer(t, func(query string) string {
+		return testARINWhois
+	})
+	iana := newTestWhoisServer(t, func(query string) string {
+		return "inetnum:      8.0.0.0 - 8.255.255.255\nrefer:        " + rir + "\n"
+	})
+
+	whoisLookup := Setup(&Config{WhoisTLDServer: iana})
+
+	info, raw, err := whoisLookup.GetIPWhois(context.Background(), "8.8.8.8")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if raw != testARINWhois {
+		t.Errorf("unexpected raw: %v", raw)
+	}
+	if info.WhoisServer != rir || info.IP != "8.8.8.8" {
+		t.Errorf("unexpected server/ip: %+v", info)
+	}
+	if info.NetName != "GOGL" || info.Organization != "Google LLC (GOGL)" || info.Country != "US" {
+		t.Errorf("expected the most specific network record, got %+v", info)
+	}
+	if !reflect.DeepEqual(info.CIDR, []string{"8.8.8.0/24"}) || info.Range != "8.8.8.0 - 8.8.8.255" {
+		t.Errorf("unexpected range: %v %v", info.Range, info.CIDR)
+	}
+	if info.AbuseEmail != "network-abuse@google.com" || info.AbusePhone != "+1-650-253-0000" {
+		t.Errorf("unexpected abuse contact: %+v", info)
+	}
+}
+
+func TestGetIPWhois_InvalidIP(t *testing.T) {
+	whoisLookup := Setup(nil)
+
+	_, _, err := whoisLookup.GetIPWhois(context.Background(), "example.com")
+	if !errors.Is(err, ErrInvalidIP) {
+		t.Fatalf("expected ErrInvalidIP, got %v", err)
+	}
+}
+
+func TestParseIPWhois_RIPE(t *testing.T) {
+	info := parseIPWhois(testRIPEWhois)
+
+	if info.NetName != "RIPE-NCC" || info.Country != "NL" || info.AbuseEmail != "abuse@ripe.net" {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	if got := rangeToCIDR(info.Range); !reflect.DeepEqual(got, []string{"193.0.0.0/21"}) {
+		t.Errorf("unexpected cidr: %v", got)
+	}
+	// The role and route objects that follow have dates of their own
+	if info.CreatedDate != "2003-03-17T12:15:57Z" || info.UpdatedDate != "2017-12-04T14:42:31Z" {
+		t.Errorf("expected the dates of the inetnum object, got %v, %v", info.CreatedDate, info.UpdatedDate)
+	}
+}
+
+func TestRangeToCIDR(t *testing.T) {
+	tests := map[string][]string{
+		"10.0.0.0 - 10.0.0.255":                               {"10.0.0.0/24"},
+		"10.0.0.1 - 10.0.0.4":                                 {"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/32"},
+		"2001:db8:: - 2001:db8:ffff:ffff:ffff:ffff:ffff:ffff": {"2001:db8::/32"},
+		"0.0.0.0 - 255.255.255.255":                           {"0.0.0.0/0"},
+	}
+	for r, want := range tests {
+		if got := rangeToCIDR(r); !reflect.DeepEqual(got, want) {
+			t.Errorf("rangeToCIDR(%v) = %v, want %v", r, got, want)
+		}
+	}
+}
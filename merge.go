@@ -0,0 +1,223 @@
+package whois
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Source is the hop a merged field was taken from.
+type Source string
+
+const (
+	SourceRegistry  Source = "registry"
+	SourceRegistrar Source = "registrar"
+)
+
+// Conflict is a field both hops returned with different values.
+type Conflict struct {
+	Field     string `json:"field"`
+	Registry  string `json:"registry"`
+	Registrar string `json:"registrar"`
+}
+
+// MergedWhois is the combined view of the registry and registrar records of a Result.
+type MergedWhois struct {
+	WhoisInfo
+	// Provenance maps every field set in WhoisInfo, by JSON path ("domain.expiration_date",
+	// "registrant"), to the hop it was taken from
+	Provenance map[string]Source `json:"provenance,omitempty"`
+	// Conflicts lists the fields the registry and registrar disagree on
+	Conflicts []Conflict `json:"conflicts,omitempty"`
+}
+
+// Merged combines RegistryWhois and RegistrarWhois into one record. Each field is taken from
+// the preferred hop and from the other one when the preferred hop does not have it:
+//   - the registry is authoritative for the domain: ID, dates, status, name servers, DNSSEC,
+//     its WHOIS server and the sponsoring registrar
+//   - the registrar is preferred for the contacts, the registrar abuse contact and the
+//     inaccuracy complaint form
+//
+// Dates, status, name servers and the registrar IANA ID are compared between the hops and
+// recorded as conflicts when both are set and differ. The merged record does not share
+// slices or contacts with the Result.
+func (result *Result) Merged() (merged MergedWhois) {
+
+	var registry, registrar WhoisInfo
+	if result.RegistryWhois != nil {
+		registry = *result.RegistryWhois
+	}
+	if result.RegistrarWhois != nil {
+		registrar = *result.RegistrarWhois
+	}
+
+	m := &merger{provenance: map[string]Source{}}
+
+	merged.Domain = m.domain(registry.Domain, registrar.Domain)
+	merged.Registrar = m.contact("registrar", registry.Registrar, registrar.Registrar, true)
+	merged.RegistrarIANAID = choose(m, "registrar_iana_id", registry.RegistrarIANAID, registrar.RegistrarIANAID, true)
+	m.compare("registrar_iana_id", registry.RegistrarIANAID, registrar.RegistrarIANAID)
+
+	merged.Registrant = m.contact("registrant", registry.Registrant, registrar.Registrant, false)
+	merged.Administrative = m.contact("administrative", registry.Administrative, registrar.Administrative, false)
+	merged.Technical = m.contact("technical", registry.Technical, registrar.Technical, false)
+	merged.Billing = m.contact("billing", registry.Billing, registrar.Billing, false)
+	if abuse := choose(m, "registrar_abuse", registry.RegistrarAbuse, registrar.RegistrarAbuse, false); abuse != nil {
+		copied := *abuse
+		merged.RegistrarAbuse = &copied
+	}
+
+	if len(m.provenance) > 0 {
+		merged.Provenance = m.provenance
+	}
+	merged.Conflicts = m.conflicts
+
+	return merged
+}
+
+// merger collects provenance and conflicts while merging.
+type merger struct {
+	provenance map[string]Source
+	conflicts  []Conflict
+}
+
+// domain merges the domain records of both hops.
+func (m *merger) domain(registry, registrar *Domain) *Domain {
+	if registry == nil && registrar == nil {
+		return nil
+	}
+	if registry == nil {
+		registry = &Domain{}
+	}
+	if registrar == nil {
+		registrar = &Domain{}
+	}
+
+	d := &Domain{
+		ID:                     choose(m, "domain.id", registry.ID, registrar.ID, true),
+		Domain:                 choose(m, "domain.domain", registry.Domain, registrar.Domain, true),
+		Punycode:               choose(m, "domain.punycode", registry.Punycode, registrar.Punycode, true),
+		Name:                   choose(m, "domain.name", registry.Name, registrar.Name, true),
+		Extension:              choose(m, "domain.extension", registry.Extension, registrar.Extension, true),
+		WhoisServer:            choose(m, "domain.whois_server", registry.WhoisServer, registrar.WhoisServer, true),
+		InaccuracyComplaintURL: choose(m, "domain.inaccuracy_complaint_url", registry.InaccuracyComplaintURL, registrar.InaccuracyComplaintURL, false),
+	}
+
+	// pick returns the hop, registry first, that has the fields checked by ok
+	pick := func(field string, ok func(d *Domain) bool) *Domain {
+		first, second := registry, registrar
+		if !ok(first) {
+			first = nil
+		}
+		if !ok(second) {
+			second = nil
+		}
+		return choose(m, field, first, second, true)
+	}
+
+	// Status and its EPP codes travel together, as do the DNSSEC flag and its DS records
+	if src := pick("domain.status", func(d *Domain) bool { return len(d.Status) > 0 }); src != nil {
+		d.Status, d.EPPStatus = slices.Clone(src.Status), slices.Clone(src.EPPStatus)
+	}
+	if src := pick("domain.name_servers", func(d *Domain) bool { return len(d.NameServers) > 0 }); src != nil {
+		d.NameServers = slices.Clone(src.NameServers)
+	}
+	// Unsigned can not be told apart from missing, so any registry record decides
+	if src := pick("domain.dnssec", func(d *Domain) bool { return d.Domain != "" || d.DNSSec }); src != nil {
+		d.DNSSec, d.DSData = src.DNSSec, slices.Clone(src.DSData)
+	}
+	if src := pick("domain.created_date", func(d *Domain) bool { return d.CreatedDate != "" }); src != nil {
+		d.CreatedDate, d.CreatedDateInTime = src.CreatedDate, cloneTime(src.CreatedDateInTime)
+	}
+	if src := pick("domain.updated_date", func(d *Domain) bool { return d.UpdatedDate != "" }); src != nil {
+		d.UpdatedDate, d.UpdatedDateInTime = src.UpdatedDate, cloneTime(src.UpdatedDateInTime)
+	}
+	if src := pick("domain.expiration_date", func(d *Domain) bool { return d.ExpirationDate != "" }); src != nil {
+		d.ExpirationDate, d.ExpirationDateInTime = src.ExpirationDate, cloneTime(src.ExpirationDateInTime)
+	}
+
+	m.compare("domain.status", joinSorted(registry.Status), joinSorted(registrar.Status))
+	m.compare("domain.name_servers", joinSorted(registry.NameServers), joinSorted(registrar.NameServers))
+	m.compareDate("domain.created_date", registry.CreatedDate, registry.CreatedDateInTime, registrar.CreatedDate, registrar.CreatedDateInTime)
+	m.compareDate("domain.expiration_date", registry.ExpirationDate, registry.ExpirationDateInTime, registrar.ExpirationDate, registrar.ExpirationDateInTime)
+	// Registrars update their own records independently of the registry, so a different
+	// update date is not a conflict
+
+	return d
+}
+
+// contact returns a copy of the contact of the preferred hop, or of the other one.
+func (m *merger) contact(field string, registry, registrar *Contact, registryFirst bool) *Contact {
+	isSet := func(c *Contact) *Contact {
+		if c == nil || *c == (Contact{}) {
+			return nil
+		}
+		return c
+	}
+	c := choose(m, field, isSet(registry), isSet(registrar), registryFirst)
+	if c == nil {
+		return nil
+	}
+	copied := *c
+	return &copied
+}
+
+// compareDate records a conflict when both dates are set and are not the same instant.
+// Unparsed dates are compared as text.
+func (m *merger) compareDate(field, registry string, registryTime *time.Time, registrar string, registrarTime *time.Time) {
+	if registryTime != nil && registrarTime != nil {
+		if !registryTime.Equal(*registrarTime) {
+			m.conflicts = append(m.conflicts, Conflict{Field: field, Registry: registry, Registrar: registrar})
+		}
+		return
+	}
+	m.compare(field, registry, registrar)
+}
+
+// compare records a conflict when both values are set and differ, ignoring case.
+func (m *merger) compare(field, registry, registrar string) {
+	if registry != "" && registrar != "" && !strings.EqualFold(registry, registrar) {
+		m.conflicts = append(m.conflicts, Conflict{Field: field, Registry: registry, Registrar: registrar})
+	}
+}
+
+// choose returns the preferred value when it is set, the other one otherwise, and records
+// the hop it came from under field.
+func choose[T comparable](m *merger, field string, registry, registrar T, registryFirst bool) T {
+	var zero T
+	first, second := registry, registrar
+	firstSource, secondSource := SourceRegistry, SourceRegistrar
+	if !registryFirst {
+		first, second = registrar, registry
+		firstSource, secondSource = SourceRegistrar, SourceRegistry
+	}
+
+	switch {
+	case first != zero:
+		m.provenance[field] = firstSource
+		return first
+	case second != zero:
+		m.provenance[field] = secondSource
+		return second
+	}
+	return zero
+}
+
+// joinSorted joins values lower cased and sorted, so that sets can be compared as text.
+func joinSorted(values []string) string {
+	lower := make([]string, 0, len(values))
+	for _, v := range values {
+		lower = append(lower, strings.ToLower(v))
+	}
+	slices.Sort(lower)
+	return strings.Join(lower, " ")
+}
+
+// cloneTime returns a copy of t.
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := *t
+	return &copied
+}
@@ -0,0 +1,101 @@
+package whois
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResultMerged(t *testing.T) {
+	registryExpiry := time.Date(2025, 8, 13, 4, 0, 0, 0, time.UTC)
+	registrarExpiry := time.Date(2026, 8, 13, 4, 0, 0, 0, time.UTC)
+	created := time.Date(1995, 8, 14, 4, 0, 0, 0, time.UTC)
+
+	result := Result{
+		RegistryWhois: &WhoisInfo{
+			Domain: &Domain{
+				Domain:               "example.com",
+				Status:               []string{"clientTransferProhibited", "clientDeleteProhibited"},
+				EPPStatus:            EPPStatuses{StatusClientTransferProhibited, StatusClientDeleteProhibited},
+				NameServers:          []string{"a.iana-servers.net", "b.iana-servers.net"},
+				CreatedDate:          "1995-08-14T04:00:00Z",
+				CreatedDateInTime:    &created,
+				ExpirationDate:       "2025-08-13T04:00:00Z",
+				ExpirationDateInTime: &registryExpiry,
+			},
+			Registrar:       &Contact{Name: "Example Registrar"},
+			RegistrarIANAID: "9999",
+		},
+		RegistrarWhois: &WhoisInfo{
+			Domain: &Domain{
+				Domain:                 "example.com",
+				Status:                 []string{"clientdeleteprohibited", "clienttransferprohibited"},
+				NameServers:            []string{"B.IANA-SERVERS.NET", "A.IANA-SERVERS.NET"},
+				CreatedDate:            "1995-08-14T04:00:00+00:00",
+				CreatedDateInTime:      &created,
+				UpdatedDate:            "2024-08-14T07:01:34Z",
+				ExpirationDate:         "2026-08-13T04:00:00Z",
+				ExpirationDateInTime:   &registrarExpiry,
+				InaccuracyComplaintURL: "https://www.icann.org/wicf/",
+			},
+			Registrar:      &Contact{Name: "Example Registrar, LLC"},
+			Registrant:     &Contact{Name: "Jane Doe"},
+			RegistrarAbuse: &AbuseContact{Email: "abuse@example-registrar.com"},
+		},
+	}
+
+	merged := result.Merged()
+
+	if merged.Domain.ExpirationDate != "2025-08-13T04:00:00Z" || !merged.Domain.ExpirationDateInTime.Equal(registryExpiry) {
+		t.Errorf("expected registry expiration date, got %v", merged.Domain.ExpirationDate)
+	}
+	if merged.Domain.UpdatedDate != "2024-08-14T07:01:34Z" || merged.Provenance["domain.updated_date"] != SourceRegistrar {
+		t.Errorf("expected registrar updated date as fallback, got %v from %v", merged.Domain.UpdatedDate, merged.Provenance["domain.updated_date"])
+	}
+	if len(merged.Domain.EPPStatus) != 2 || merged.Provenance["domain.status"] != SourceRegistry {
+		t.Errorf("expected registry status, got %v", merged.Domain.EPPStatus)
+	}
+	if merged.Registrar.Name != "Example Registrar" || merged.RegistrarIANAID != "9999" {
+		t.Errorf("expected registry registrar, got %+v %v", merged.Registrar, merged.RegistrarIANAID)
+	}
+	if merged.Registrant == nil || merged.Registrant.Name != "Jane Doe" || merged.Provenance["registrant"] != SourceRegistrar {
+		t.Errorf("expected registrar registrant, got %+v", merged.Registrant)
+	}
+	if merged.RegistrarAbuse == nil || merged.Domain.InaccuracyComplaintURL == "" {
+		t.Errorf("expected registrar abuse contact and complaint form, got %+v", merged)
+	}
+	if _, ok := merged.Provenance["technical"]; ok {
+		t.Error("expected no provenance for missing contacts")
+	}
+
+	// Name servers and status only differ in case and order, the created dates are the same instant
+	if len(merged.Conflicts) != 1 {
+		t.Fatalf("expected one conflict, got %+v", merged.Conflicts)
+	}
+	expected := Conflict{Field: "domain.expiration_date", Registry: "2025-08-13T04:00:00Z", Registrar: "2026-08-13T04:00:00Z"}
+	if merged.Conflicts[0] != expected {
+		t.Errorf("unexpected conflict: %+v", merged.Conflicts[0])
+	}
+
+	// The merged record is a copy
+	merged.Domain.NameServers[0] = "changed"
+	merged.Registrant.Name = "changed"
+	if result.RegistryWhois.Domain.NameServers[0] != "a.iana-servers.net" || result.RegistrarWhois.Registrant.Name != "Jane Doe" {
+		t.Error("expected Merged not to share data with the result")
+	}
+}
+
+func TestResultMerged_SingleHop(t *testing.T) {
+	result := Result{RegistryWhois: &WhoisInfo{Domain: &Domain{Domain: "example.de", NameServers: []string{"ns1.example.de"}}}}
+
+	merged := result.Merged()
+	if merged.Domain == nil || merged.Domain.Domain != "example.de" || len(merged.Domain.NameServers) != 1 {
+		t.Errorf("unexpected merged domain: %+v", merged.Domain)
+	}
+	if len(merged.Conflicts) != 0 {
+		t.Errorf("expected no conflicts, got %+v", merged.Conflicts)
+	}
+
+	if merged := (&Result{}).Merged(); merged.Domain != nil || merged.Provenance != nil {
+		t.Errorf("expected empty merge, got %+v", merged)
+	}
+}
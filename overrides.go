@@ -0,0 +1,164 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidConfig is returned by Config.Validate.
+	ErrInvalidConfig = errors.New("Invalid config")
+)
+
+// Validate checks Config.TLDServers and Config.ServerRewrites. New returns this error, Setup
+// leaves the invalid entries out.
+func (config *Config) Validate() (err error) {
+	var errs []error
+
+	for suffix, servers := range config.TLDServers {
+		if suffixErr := validateSuffix(suffix); suffixErr != nil {
+			errs = append(errs, fmt.Errorf("%w: tld_servers: %w", ErrInvalidConfig, suffixErr))
+		}
+		if serverErr := validateServers(servers); serverErr != nil {
+			errs = append(errs, fmt.Errorf("%w: tld_servers[%q]: %w", ErrInvalidConfig, suffix, serverErr))
+		}
+	}
+
+	for from, to := range config.ServerRewrites {
+		if serverErr := validateServer(from); serverErr != nil {
+			errs = append(errs, fmt.Errorf("%w: server_rewrites: key %q: %w", ErrInvalidConfig, from, serverErr))
+		}
+		if serverErr := validateServer(to); serverErr != nil {
+			errs = append(errs, fmt.Errorf("%w: server_rewrites[%q]: %w", ErrInvalidConfig, from, serverErr))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// withoutInvalid returns a copy of config without the entries failing Validate.
+func (config *Config) withoutInvalid() *Config {
+	valid := *config
+
+	valid.TLDServers = make(map[string][]string, len(config.TLDServers))
+	for suffix, servers := range config.TLDServers {
+		if validateSuffix(suffix) == nil && validateServers(servers) == nil {
+			valid.TLDServers[suffix] = servers
+		}
+	}
+
+	valid.ServerRewrites = make(map[string]string, len(config.ServerRewrites))
+	for from, to := range config.ServerRewrites {
+		if validateServer(from) == nil && validateServer(to) == nil {
+			valid.ServerRewrites[from] = to
+		}
+	}
+
+	return &valid
+}
+
+// validateSuffix checks that suffix is a TLD or public suffix.
+func validateSuffix(suffix string) error {
+	if normalizeSuffix(suffix) == "" || strings.ContainsAny(suffix, " \t:/") {
+		return fmt.Errorf("invalid TLD %q", suffix)
+	}
+	return nil
+}
+
+// validateServers checks that servers lists at least one server and only valid ones.
+func validateServers(servers []string) error {
+	if len(servers) == 0 {
+		return errors.New("no server")
+	}
+	for _, server := range servers {
+		if err := validateServer(server); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateServer checks that server is a host or host:port.
+func validateServer(server string) error {
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		host, port = server, ""
+	}
+	if host == "" || strings.ContainsAny(host, " \t:/") {
+		return fmt.Errorf("invalid server %q", server)
+	}
+	if port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("invalid port in %q", server)
+		}
+	}
+	return nil
+}
+
+// normalizeRewrites lower cases the hosts rewrites are keyed by.
+func normalizeRewrites(rewrites map[string]string) map[string]string {
+	normalized := make(map[string]string, len(rewrites))
+	for from, to := range rewrites {
+		normalized[strings.ToLower(from)] = to
+	}
+	return normalized
+}
+
+// overrideServer returns the first server configured in Config.TLDServers for suffix.
+func (wl *WhoisLookup) overrideServer(suffix string) (server string, ok bool) {
+	servers, ok := wl.tldServers[normalizeSuffix(suffix)]
+	if !ok {
+		return "", false
+	}
+	return servers[0], true
+}
+
+// registryServers returns the servers to query for suffix in order: whoisServer, as returned by
+// getWhoisServerForSuffix, followed by the other servers Config.TLDServers sets for it.
+func (wl *WhoisLookup) registryServers(suffix, whoisServer string) []string {
+	suffix = normalizeSuffix(suffix)
+	servers, ok := wl.tldServers[suffix]
+	if !ok && suffixWhoisServers[suffix] == "" {
+		servers = wl.tldServers[suffix[strings.LastIndex(suffix, ".")+1:]]
+	}
+	if len(servers) == 0 || servers[0] != whoisServer {
+		return []string{whoisServer}
+	}
+	return servers
+}
+
+// queryRegistry calls query with each of the registryServers of suffix until one succeeds and
+// returns the server that answered, or the last one tried.
+func (wl *WhoisLookup) queryRegistry(ctx context.Context, suffix, whoisServer string, query func(server string) error) (server string, err error) {
+	for _, server = range wl.registryServers(suffix, whoisServer) {
+		if err = query(server); err == nil || ctx.Err() != nil {
+			return server, err
+		}
+	}
+	return server, err
+}
+
+// rewriteAddress applies Config.ServerRewrites to address (host:port). A rewrite of the exact
+// host:port wins over one of the host. A rewrite without a port keeps the port of address.
+func (wl *WhoisLookup) rewriteAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+
+	target, ok := wl.serverRewrites[strings.ToLower(address)]
+	if !ok {
+		if target, ok = wl.serverRewrites[strings.ToLower(host)]; !ok {
+			return address
+		}
+	}
+
+	if _, _, err = net.SplitHostPort(target); err == nil {
+		return target
+	}
+	return net.JoinHostPort(target, port)
+}
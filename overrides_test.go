@@ -0,0 +1,163 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	valid := &Config{
+		TLDServers:     map[string][]string{"example": {"whois.nic.example"}, ".co.uk": {"127.0.0.1:4343", "whois.nic.uk"}},
+		ServerRewrites: map[string]string{"whois.verisign-grs.com": "mirror.internal:4343", "whois.nic.uk:43": "mirror.internal"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	tests := []*Config{
+		{TLDServers: map[string][]string{"": {"whois.nic.example"}}},
+		{TLDServers: map[string][]string{"example": {}}},
+		{TLDServers: map[string][]string{"example": {""}}},
+		{TLDServers: map[string][]string{"example": {"whois.nic.example", "whois.nic.example:0"}}},
+		{TLDServers: map[string][]string{"example": {"whois://whois.nic.example"}}},
+		{ServerRewrites: map[string]string{"whois.example.com": "mirror.internal:port"}},
+		{ServerRewrites: map[string]string{"": "mirror.internal"}},
+	}
+	for _, config := range tests {
+		if err := config.Validate(); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig for %+v, got %v", config, err)
+		}
+	}
+
+	invalid := &Config{
+		DisableBootstrap: true,
+		TLDServers:       map[string][]string{"example": {""}, "test": {"whois.nic.test"}},
+	}
+	if whoisLookup, err := New(invalid); !errors.Is(err, ErrInvalidConfig) || whoisLookup != nil {
+		t.Errorf("expected New to fail with ErrInvalidConfig, got %v, %v", whoisLookup, err)
+	}
+
+	// Setup leaves the invalid entry out and keeps the valid one
+	whoisLookup := Setup(invalid)
+	if _, ok := whoisLookup.overrideServer("example"); ok {
+		t.Error("expected the invalid override to be left out")
+	}
+	if server, ok := whoisLookup.overrideServer("test"); !ok || server != "whois.nic.test" {
+		t.Errorf("expected the valid override to be kept, got %v, %v", server, ok)
+	}
+	if len(invalid.TLDServers) != 2 {
+		t.Errorf("expected Setup not to modify config.TLDServers, got %v", invalid.TLDServers)
+	}
+}
+
+func TestTLDServers(t *testing.T) {
+	var ianaQueries atomic.Int32
+	iana := newTestWhoisServer(t, func(query string) string {
+		ianaQueries.Add(1)
+		return "whois: whois.iana-answer.test\n"
+	})
+
+	whoisLookup := Setup(&Config{
+		WhoisTLDServer: iana,
+		TLDServers:     map[string][]string{"COM": {"whois.private.test"}, "co.uk": {"whois.couk.test:4343"}},
+	})
+	ctx := context.Background()
+
+	tests := []struct {
+		suffix string
+		want   string
+	}{
+		{"com", "whois.private.test"},
+		{"co.uk", "whois.couk.test:4343"},
+		{"ac.uk", "whois.ja.net"},
+		{"uk", "whois.iana-answer.test"},
+	}
+	for _, tt := range tests {
+		if server, err := whoisLookup.getWhoisServerForSuffix(ctx, tt.suffix, nil); err != nil || server != tt.want {
+			t.Errorf("getWhoisServerForSuffix(%v) = %v, %v, want %v", tt.suffix, server, err, tt.want)
+		}
+	}
+	if server, err := whoisLookup.GetTLDWhoisServer(ctx, "com"); err != nil || server != "whois.private.test" {
+		t.Errorf("expected override for GetTLDWhoisServer, got %v, %v", server, err)
+	}
+	// Only uk, which has no override, was asked at IANA
+	if ianaQueries.Load() != 1 {
+		t.Errorf("expected one IANA query, got %d", ianaQueries.Load())
+	}
+}
+
+func TestServerRewrites(t *testing.T) {
+	mirror := newTestWhoisServer(t, func(query string) string {
+		return testReferralWhois("whois.registrar.test")
+	})
+	registrarMirror := newTestWhoisServer(t, func(query string) string {
+		return testReferralWhois("")
+	})
+	mirrorHost, mirrorPort, _ := net.SplitHostPort(registrarMirror)
+	port, _ := strconv.Atoi(mirrorPort)
+
+	whoisLookup := Setup(&Config{
+		TLDServers: map[string][]string{"com": {"whois.registry.test"}},
+		ServerRewrites: map[string]string{
+			"whois.registry.test":  mirror,
+			"WHOIS.registrar.test": mirrorHost,
+		},
+		// The rewrite without a port keeps the port of the server, found in its profile
+		ServerProfiles: map[string]ServerProfile{"whois.registrar.test": {Port: port}},
+	})
+
+	result, err := whoisLookup.GetWhoisWithLocalAddr(context.Background(), "example.com", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RegistryWhoisServer != "whois.registry.test" || len(result.Hops) != 2 || result.Hops[1].Server != "whois.registrar.test" {
+		t.Errorf("expected hops to keep the configured servers, got %v, %+v", result.RegistryWhoisServer, result.Hops)
+	}
+	if result.RegistrarWhois == nil || !strings.EqualFold(result.RegistrarWhois.Domain.Domain, "example.com") {
+		t.Errorf("expected the registrar record from the mirror, got %+v", result.RegistrarWhois)
+	}
+}
+
+func TestTLDServersFailover(t *testing.T) {
+	// Nothing listens on a port just released
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := listener.Addr().String()
+	listener.Close()
+
+	up := newTestWhoisServer(t, func(query string) string {
+		return testReferralWhois("")
+	})
+
+	whoisLookup := Setup(&Config{
+		DisableBootstrap: true,
+		RetryPolicy:      RetryPolicy{MaxAttempts: 1},
+		TLDServers:       map[string][]string{"com": {down, up}},
+	})
+	ctx := context.Background()
+
+	// The record has no registrar WHOIS server, only the registry is asked
+	result, err := whoisLookup.GetWhoisWithLocalAddr(ctx, "example.com", nil)
+	if !errors.Is(err, ErrRegistryMissingWhoisServer) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RegistryWhoisServer != up || result.RegistryWhois == nil {
+		t.Errorf("expected the second server to answer, got %v, %+v", result.RegistryWhoisServer, result.RegistryWhois)
+	}
+
+	if _, _, err = whoisLookup.GetRegistryWhois(ctx, "example.com"); err != nil {
+		t.Errorf("expected GetRegistryWhois to fail over, got %v", err)
+	}
+
+	// co.com has no override of its own and uses the servers of com
+	if servers := whoisLookup.registryServers("co.com", down); len(servers) != 2 {
+		t.Errorf("expected the servers of com for co.com, got %v", servers)
+	}
+}
@@ -0,0 +1,84 @@
+package whois
+
+import (
+	"strings"
+)
+
+// Parser turns a raw WHOIS response from server into WhoisInfo. Parsers should return
+// ErrDomainNotFound, ErrReservedDomain, ErrPremiumDomain or ErrBlockedDomain (wrapped or not)
+// when the response states the domain's registration state instead of a record.
+type Parser interface {
+	Parse(raw, server string) (WhoisInfo, error)
+}
+
+// ParserFunc adapts a function to the Parser interface.
+type ParserFunc func(raw, server string) (WhoisInfo, error)
+
+// Parse calls f(raw, server).
+func (f ParserFunc) Parse(raw, server string) (WhoisInfo, error) {
+	return f(raw, server)
+}
+
+// DefaultParser is the built in parser backed by github.com/likexian/whois-parser.
+var DefaultParser Parser = whoisparserParser{}
+
+type whoisparserParser struct{}
+
+func (whoisparserParser) Parse(raw, server string) (WhoisInfo, error) {
+	return wrapParser(raw)
+}
+
+// parser returns the parser for a response from server about domain. A parser registered for
+// the server wins over one registered for the domain's suffix (longest first), which wins over
+// Config.Parser and finally DefaultParser.
+func (wl *WhoisLookup) parser(server, domain string) Parser {
+	if p, ok := wl.serverParsers[serverHost(server)]; ok {
+		return p
+	}
+
+	for suffix := strings.ToLower(domain); suffix != ""; {
+		if p, ok := wl.tldParsers[suffix]; ok {
+			return p
+		}
+		i := strings.Index(suffix, ".")
+		if i < 0 {
+			break
+		}
+		suffix = suffix[i+1:]
+	}
+
+	if wl.config.Parser != nil {
+		return wl.config.Parser
+	}
+	return DefaultParser
+}
+
+// parse parses a raw response from server about domain with the parser registered for it.
+// ICANN fields the parser does not know about are filled in from the raw response and
+// redacted contacts are flagged.
+func (wl *WhoisLookup) parse(raw, server, domain string) (info WhoisInfo, err error) {
+	if info, err = wl.parser(server, domain).Parse(raw, server); err != nil {
+		return info, err
+	}
+
+	enrichWhoisInfo(&info, raw)
+	detectRedactions(&info)
+
+	return info, err
+}
+
+// normalizeParsers keys server parsers by lower case host and TLD parsers by lower case suffix.
+func normalizeParsers(parsers map[string]Parser, key func(string) string) map[string]Parser {
+	normalized := make(map[string]Parser, len(parsers))
+	for k, p := range parsers {
+		if p != nil {
+			normalized[key(k)] = p
+		}
+	}
+	return normalized
+}
+
+// normalizeSuffix lower cases a TLD or public suffix and drops leading and trailing dots.
+func normalizeSuffix(suffix string) string {
+	return strings.Trim(strings.ToLower(suffix), ".")
+}
@@ -0,0 +1,545 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/chrispassas/whois"
+)
+
+// field is one "key: value" line of a block.
+type field struct {
+	key   string
+	value string
+}
+
+// splitBlocks splits a RIPE style response (.fr, .br) into blank line separated blocks of
+// fields. Comment lines starting with "%" are skipped.
+func splitBlocks(raw string) (blocks [][]field) {
+	var block []field
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if len(block) > 0 {
+				blocks = append(blocks, block)
+				block = nil
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "%") {
+			continue
+		}
+		if key, value, ok := keyValue(line); ok {
+			block = append(block, field{key: key, value: value})
+		}
+	}
+	if len(block) > 0 {
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// splitSections splits an indented response (.uk, .nl) into sections. A line ending in ":"
+// starts a section, the lines below it are its values. Lines with a value on the same line
+// ("Status: active") are sections of one value.
+func splitSections(raw string, stop ...string) (sections map[string][]string) {
+	sections = map[string][]string{}
+
+	var current string
+	for _, line := range strings.Split(raw, "\n") {
+		trimmed := strings.TrimSpace(line)
+		for _, s := range stop {
+			if strings.HasPrefix(trimmed, s) {
+				return sections
+			}
+		}
+		if trimmed == "" {
+			continue
+		}
+
+		if strings.HasSuffix(trimmed, ":") && !strings.HasPrefix(line, "        ") {
+			current = strings.ToLower(strings.TrimSuffix(trimmed, ":"))
+			sections[current] = sections[current]
+			continue
+		}
+
+		// "Key: value" at the top level, as in .nl records
+		if line == trimmed {
+			if key, value, ok := keyValue(trimmed); ok {
+				sections[key] = append(sections[key], value)
+				current = ""
+				continue
+			}
+		}
+
+		if current != "" {
+			sections[current] = append(sections[current], trimmed)
+		}
+	}
+	return sections
+}
+
+// first returns the first value or "".
+func first(values []string) string {
+	if len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+var nominetTagRegexp = regexp.MustCompile(`^(.*?)\s*\[Tag = ([^\]]+)\]$`)
+
+// parseUK parses Nominet (.uk) records.
+func parseUK(raw string) (info whois.WhoisInfo, err error) {
+	sections := splitSections(raw, "WHOIS lookup made at", "--")
+
+	name := first(sections["domain name"])
+	if name == "" {
+		return info, err
+	}
+	domain := newDomain(name)
+
+	if values := sections["registrar"]; len(values) > 0 {
+		registrar := &whois.Contact{Name: values[0]}
+		if m := nominetTagRegexp.FindStringSubmatch(values[0]); m != nil {
+			registrar.Name, registrar.ID = m[1], m[2]
+		}
+		for _, value := range values[1:] {
+			if key, v, ok := keyValue(value); ok && key == "url" {
+				registrar.ReferralURL = v
+			}
+		}
+		info.Registrar = registrar
+	}
+
+	if values := sections["registrant"]; len(values) > 0 {
+		info.Registrant = &whois.Contact{Name: values[0]}
+		if address := sections["registrant's address"]; len(address) > 0 {
+			info.Registrant.Street = strings.Join(address, ", ")
+		}
+	}
+
+	for _, value := range sections["relevant dates"] {
+		key, v, ok := keyValue(value)
+		if !ok {
+			continue
+		}
+		switch key {
+		case "registered on":
+			setDate(v, &domain.CreatedDate, &domain.CreatedDateInTime)
+		case "expiry date":
+			setDate(v, &domain.ExpirationDate, &domain.ExpirationDateInTime)
+		case "last updated":
+			setDate(v, &domain.UpdatedDate, &domain.UpdatedDateInTime)
+		}
+	}
+
+	for _, value := range sections["registration status"] {
+		domain.Status = appendUnique(domain.Status, strings.TrimSuffix(value, "."))
+	}
+	for _, value := range sections["name servers"] {
+		domain.NameServers = appendUnique(domain.NameServers, nameServer(value))
+	}
+	domain.DNSSec = dnssecSigned(first(sections["dnssec"]))
+
+	info.Domain = domain
+	return info, err
+}
+
+// denicContactRoles maps DENIC contact sections to WhoisInfo contacts.
+var denicContactRoles = map[string]string{
+	"[holder]":  "registrant",
+	"[admin-c]": "admin",
+	"[tech-c]":  "tech",
+}
+
+// parseDE parses DENIC (.de) records.
+func parseDE(raw string) (info whois.WhoisInfo, err error) {
+
+	var (
+		domain   *whois.Domain
+		contacts = map[string]*whois.Contact{}
+		contact  *whois.Contact
+	)
+
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "%") {
+			continue
+		}
+
+		// "[Tech-C]" starts a contact section, other sections are skipped
+		if strings.HasPrefix(line, "[") {
+			contact = nil
+			if role, ok := denicContactRoles[strings.ToLower(line)]; ok {
+				contact = &whois.Contact{}
+				contacts[role] = contact
+			}
+			continue
+		}
+
+		key, value, ok := keyValue(line)
+		if !ok || value == "" {
+			continue
+		}
+
+		if contact != nil {
+			switch key {
+			case "name":
+				contact.Name = value
+			case "organisation":
+				contact.Organization = value
+			case "address":
+				contact.Street = joinNonEmpty(", ", contact.Street, value)
+			case "postalcode":
+				contact.PostalCode = value
+			case "city":
+				contact.City = value
+			case "countrycode":
+				contact.Country = value
+			case "phone":
+				contact.Phone = value
+			case "fax":
+				contact.Fax = value
+			case "email":
+				contact.Email = value
+			}
+			continue
+		}
+
+		switch key {
+		case "domain":
+			if domain == nil {
+				domain = newDomain(value)
+			}
+		case "nserver":
+			if domain != nil {
+				domain.NameServers = appendUnique(domain.NameServers, nameServer(value))
+			}
+		case "dnskey":
+			if domain != nil {
+				domain.DNSSec = true
+			}
+		case "status":
+			if domain != nil {
+				domain.Status = appendUnique(domain.Status, value)
+			}
+		case "changed":
+			if domain != nil {
+				setDate(value, &domain.UpdatedDate, &domain.UpdatedDateInTime)
+			}
+		}
+	}
+
+	if domain == nil {
+		return info, err
+	}
+	info.Domain = domain
+	info.Registrant = contacts["registrant"]
+	info.Administrative = contacts["admin"]
+	info.Technical = contacts["tech"]
+
+	return info, err
+}
+
+// jprsLineRegexp matches "a. [Domain Name]   EXAMPLE.JP" and "[State]   Connected (2025/01/31)".
+var jprsLineRegexp = regexp.MustCompile(`^(?:[a-z]\.\s*)?\[([^\]]+)\]\s*(.*)$`)
+
+// parseJP parses JPRS (.jp) records in English ("/e") output.
+func parseJP(raw string) (info whois.WhoisInfo, err error) {
+
+	var (
+		domain     *whois.Domain
+		registrant whois.Contact
+		admin      whois.Contact
+		tech       whois.Contact
+	)
+
+	for _, line := range strings.Split(raw, "\n") {
+		m := jprsLineRegexp.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		key, value := strings.ToLower(m[1]), strings.TrimSpace(m[2])
+		if value == "" {
+			continue
+		}
+
+		if key == "domain name" {
+			if domain == nil {
+				domain = newDomain(value)
+			}
+			continue
+		}
+		if domain == nil {
+			continue
+		}
+
+		switch key {
+		case "organization", "registrant":
+			registrant.Organization = value
+		case "administrative contact":
+			admin.ID = value
+		case "technical contact":
+			tech.ID = value
+		case "name server":
+			domain.NameServers = appendUnique(domain.NameServers, nameServer(value))
+		case "signing key":
+			domain.DNSSec = true
+		case "state", "status":
+			// "Connected (2025/01/31)"
+			if i := strings.Index(value, "("); i > 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+			domain.Status = appendUnique(domain.Status, value)
+		case "registered date", "created on":
+			setDate(value, &domain.CreatedDate, &domain.CreatedDateInTime)
+		case "last update", "last updated":
+			setDate(value, &domain.UpdatedDate, &domain.UpdatedDateInTime)
+		case "expires on", "expiration date":
+			setDate(value, &domain.ExpirationDate, &domain.ExpirationDateInTime)
+		}
+	}
+
+	if domain == nil {
+		return info, err
+	}
+	info.Domain = domain
+	if registrant != (whois.Contact{}) {
+		info.Registrant = &registrant
+	}
+	if admin != (whois.Contact{}) {
+		info.Administrative = &admin
+	}
+	if tech != (whois.Contact{}) {
+		info.Technical = &tech
+	}
+
+	return info, err
+}
+
+// parseFR parses AFNIC (.fr) records: a domain block followed by registrar and nic-hdl blocks.
+func parseFR(raw string) (info whois.WhoisInfo, err error) {
+
+	var (
+		domain    *whois.Domain
+		handles   = map[string]string{}
+		contacts  = map[string]*whois.Contact{}
+		registrar *whois.Contact
+		statuses  []string
+		epp       []string
+	)
+
+	for _, block := range splitBlocks(raw) {
+		switch block[0].key {
+		case "domain":
+			if domain != nil {
+				continue
+			}
+			domain = newDomain(block[0].value)
+			for _, f := range block[1:] {
+				switch f.key {
+				case "status":
+					statuses = appendUnique(statuses, f.value)
+				case "eppstatus":
+					epp = appendUnique(epp, f.value)
+				case "holder-c":
+					handles["registrant"] = f.value
+				case "admin-c":
+					handles["admin"] = f.value
+				case "tech-c":
+					handles["tech"] = f.value
+				case "registrar":
+					registrar = &whois.Contact{Name: f.value}
+				case "expiry date":
+					setDate(f.value, &domain.ExpirationDate, &domain.ExpirationDateInTime)
+				case "created":
+					setDate(f.value, &domain.CreatedDate, &domain.CreatedDateInTime)
+				case "last-update":
+					setDate(f.value, &domain.UpdatedDate, &domain.UpdatedDateInTime)
+				case "nserver":
+					domain.NameServers = appendUnique(domain.NameServers, nameServer(f.value))
+				case "key1-tag", "ds-record", "dnssec":
+					domain.DNSSec = domain.DNSSec || dnssecSigned(f.value)
+				}
+			}
+		case "nserver":
+			if domain == nil {
+				continue
+			}
+			for _, f := range block {
+				if f.key == "nserver" {
+					domain.NameServers = appendUnique(domain.NameServers, nameServer(f.value))
+				}
+			}
+		case "registrar":
+			if registrar == nil || registrar.Name != block[0].value {
+				continue
+			}
+			for _, f := range block[1:] {
+				switch f.key {
+				case "address":
+					registrar.Street = joinNonEmpty(", ", registrar.Street, f.value)
+				case "country":
+					registrar.Country = f.value
+				case "phone":
+					registrar.Phone = f.value
+				case "fax-no":
+					registrar.Fax = f.value
+				case "e-mail":
+					registrar.Email = f.value
+				case "website":
+					registrar.ReferralURL = f.value
+				}
+			}
+		case "nic-hdl":
+			contact := &whois.Contact{ID: block[0].value}
+			organization := false
+			for _, f := range block[1:] {
+				switch f.key {
+				case "type":
+					organization = strings.EqualFold(f.value, "ORGANIZATION")
+				case "contact":
+					contact.Name = f.value
+				case "address":
+					contact.Street = joinNonEmpty(", ", contact.Street, f.value)
+				case "country":
+					contact.Country = f.value
+				case "phone":
+					contact.Phone = f.value
+				case "fax-no":
+					contact.Fax = f.value
+				case "e-mail":
+					contact.Email = f.value
+				}
+			}
+			if organization {
+				contact.Organization, contact.Name = contact.Name, ""
+			}
+			contacts[contact.ID] = contact
+		}
+	}
+
+	if domain == nil {
+		return info, err
+	}
+
+	// EPP statuses are more precise than the AFNIC state when both are given
+	domain.Status = statuses
+	if len(epp) > 0 {
+		domain.Status = epp
+	}
+	info.Domain = domain
+	info.Registrar = registrar
+	info.Registrant = contacts[handles["registrant"]]
+	info.Administrative = contacts[handles["admin"]]
+	info.Technical = contacts[handles["tech"]]
+
+	return info, err
+}
+
+// parseNL parses SIDN (.nl) records.
+func parseNL(raw string) (info whois.WhoisInfo, err error) {
+	sections := splitSections(raw, "Record maintained by", "Copyright notice")
+
+	name := first(sections["domain name"])
+	if name == "" {
+		return info, err
+	}
+	domain := newDomain(name)
+
+	for _, status := range sections["status"] {
+		domain.Status = appendUnique(domain.Status, status)
+	}
+	for _, value := range sections["domain nameservers"] {
+		domain.NameServers = appendUnique(domain.NameServers, nameServer(value))
+	}
+	domain.DNSSec = dnssecSigned(first(sections["dnssec"]))
+	setDate(first(sections["creation date"]), &domain.CreatedDate, &domain.CreatedDateInTime)
+	setDate(first(sections["updated date"]), &domain.UpdatedDate, &domain.UpdatedDateInTime)
+	info.Domain = domain
+
+	if values := sections["registrar"]; len(values) > 0 {
+		info.Registrar = &whois.Contact{Name: values[0]}
+		if len(values) > 1 {
+			info.Registrar.Street = strings.Join(values[1:], ", ")
+		}
+	}
+
+	return info, err
+}
+
+// parseBR parses registro.br (.br) records: a domain block followed by nic-hdl-br blocks.
+func parseBR(raw string) (info whois.WhoisInfo, err error) {
+
+	var (
+		domain     *whois.Domain
+		registrant *whois.Contact
+		handles    = map[string]string{}
+		contacts   = map[string]*whois.Contact{}
+	)
+
+	for _, block := range splitBlocks(raw) {
+		switch block[0].key {
+		case "domain":
+			if domain != nil {
+				continue
+			}
+			domain = newDomain(block[0].value)
+			for _, f := range block[1:] {
+				switch f.key {
+				case "owner":
+					registrant = &whois.Contact{Organization: f.value}
+				case "owner-c":
+					handles["registrant"] = f.value
+				case "admin-c":
+					handles["admin"] = f.value
+				case "tech-c":
+					handles["tech"] = f.value
+				case "nserver":
+					domain.NameServers = appendUnique(domain.NameServers, nameServer(f.value))
+				case "ds-record":
+					domain.DNSSec = true
+				case "created":
+					setDate(f.value, &domain.CreatedDate, &domain.CreatedDateInTime)
+				case "changed":
+					setDate(f.value, &domain.UpdatedDate, &domain.UpdatedDateInTime)
+				case "expires":
+					setDate(f.value, &domain.ExpirationDate, &domain.ExpirationDateInTime)
+				case "status":
+					domain.Status = appendUnique(domain.Status, f.value)
+				}
+			}
+		case "nic-hdl-br":
+			contact := &whois.Contact{ID: block[0].value}
+			for _, f := range block[1:] {
+				switch f.key {
+				case "person":
+					contact.Name = f.value
+				case "e-mail":
+					contact.Email = f.value
+				case "country":
+					contact.Country = f.value
+				}
+			}
+			contacts[contact.ID] = contact
+		}
+	}
+
+	if domain == nil {
+		return info, err
+	}
+	info.Domain = domain
+
+	if c := contacts[handles["registrant"]]; c != nil {
+		if registrant == nil {
+			registrant = &whois.Contact{}
+		}
+		registrant.ID, registrant.Name, registrant.Email, registrant.Country = c.ID, c.Name, c.Email, c.Country
+	}
+	info.Registrant = registrant
+	info.Administrative = contacts[handles["admin"]]
+	info.Technical = contacts[handles["tech"]]
+
+	return info, err
+}
@@ -0,0 +1,187 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/chrispassas/whois"
+)
+
+// contactRoles maps the key prefixes of ICANN style contacts to the WhoisInfo contact.
+var contactRoles = []string{"registrant", "admin", "tech", "billing"}
+
+// parseICANN parses the ICANN RAA 2013 key/value format used by gTLD registries and
+// registrars, Verisign thin records and the auDA layout, which shares most keys.
+func parseICANN(raw string) (info whois.WhoisInfo, err error) {
+
+	var (
+		domain    *whois.Domain
+		registrar whois.Contact
+		contacts  = map[string]*whois.Contact{}
+		statuses  []string
+		servers   []string
+		dnssec    string
+		dsData    []whois.DSRecord
+		complaint string
+	)
+
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+
+		// ">>> Last update of WHOIS database" ends the record, the terms of use follow
+		if strings.HasPrefix(line, ">>>") {
+			break
+		}
+		if line == "" || strings.HasPrefix(line, "%") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := keyValue(line)
+		if !ok || value == "" {
+			continue
+		}
+
+		switch key {
+		case "domain name":
+			if domain == nil {
+				domain = newDomain(value)
+			}
+			continue
+		case "name server", "nserver":
+			servers = appendUnique(servers, nameServer(value))
+			continue
+		case "domain status", "status":
+			statuses = appendUnique(statuses, eppStatus(value))
+			continue
+		case "dnssec":
+			dnssec = value
+			continue
+		case "dnssec ds data":
+			if ds, err := whois.ParseDSRecord(value); err == nil {
+				dsData = append(dsData, ds)
+			}
+			continue
+		case "url of the icann whois inaccuracy complaint form":
+			complaint = value
+			continue
+		}
+
+		if domain == nil {
+			continue
+		}
+
+		switch key {
+		case "registry domain id":
+			domain.ID = value
+		case "registrar whois server":
+			domain.WhoisServer = value
+		case "updated date", "last modified":
+			setDate(value, &domain.UpdatedDate, &domain.UpdatedDateInTime)
+		case "creation date":
+			setDate(value, &domain.CreatedDate, &domain.CreatedDateInTime)
+		case "registry expiry date", "registrar registration expiration date", "expiry date":
+			setDate(value, &domain.ExpirationDate, &domain.ExpirationDateInTime)
+		case "registrar", "registrar name":
+			registrar.Name = value
+		case "registrar iana id":
+			registrar.ID = value
+		case "registrar url":
+			registrar.ReferralURL = value
+		case "registrar abuse contact email":
+			registrar.Email = value
+		case "registrar abuse contact phone":
+			registrar.Phone = value
+		default:
+			setContactField(contacts, key, value)
+		}
+	}
+
+	if domain == nil {
+		return info, err
+	}
+
+	domain.Status = statuses
+	domain.NameServers = servers
+	domain.DNSSec = dnssecSigned(dnssec)
+	domain.DSData = dsData
+	domain.InaccuracyComplaintURL = complaint
+	for _, status := range statuses {
+		if s, ok := whois.ParseEPPStatus(status); ok && !domain.EPPStatus.Has(s) {
+			domain.EPPStatus = append(domain.EPPStatus, s)
+		}
+	}
+	info.Domain = domain
+
+	if registrar != (whois.Contact{}) {
+		info.Registrar = &registrar
+	}
+	info.RegistrarIANAID = registrar.ID
+	if registrar.Email != "" || registrar.Phone != "" {
+		info.RegistrarAbuse = &whois.AbuseContact{Email: registrar.Email, Phone: registrar.Phone}
+	}
+	info.Registrant = contacts["registrant"]
+	info.Administrative = contacts["admin"]
+	info.Technical = contacts["tech"]
+	info.Billing = contacts["billing"]
+
+	return info, err
+}
+
+// setContactField stores an ICANN style contact line ("Registrant Phone Ext: 123",
+// "Registry Tech ID: T1", "Tech Contact Name: ..." in auDA records) in its contact.
+func setContactField(contacts map[string]*whois.Contact, key, value string) {
+	key = strings.TrimPrefix(key, "registry ")
+
+	var role, field string
+	for _, r := range contactRoles {
+		if rest, ok := strings.CutPrefix(key, r); ok {
+			role, field = r, strings.TrimSpace(rest)
+			break
+		}
+	}
+	if role == "" {
+		return
+	}
+	field = strings.TrimPrefix(field, "contact ")
+
+	contact := contacts[role]
+	if contact == nil {
+		contact = &whois.Contact{}
+	}
+
+	switch field {
+	case "id":
+		// auDA follows "Registrant Contact ID" with the eligibility "Registrant ID"
+		if contact.ID == "" {
+			contact.ID = value
+		}
+	case "name":
+		contact.Name = value
+	case "", "organization", "organisation":
+		// auDA names the registrant organization "Registrant:"
+		contact.Organization = value
+	case "street":
+		contact.Street = joinNonEmpty(", ", contact.Street, value)
+	case "city":
+		contact.City = value
+	case "state/province", "state", "province":
+		contact.Province = value
+	case "postal code":
+		contact.PostalCode = value
+	case "country", "country code":
+		contact.Country = value
+	case "phone":
+		contact.Phone = value
+	case "phone ext":
+		contact.PhoneExt = value
+	case "fax":
+		contact.Fax = value
+	case "fax ext":
+		contact.FaxExt = value
+	case "email":
+		contact.Email = value
+	default:
+		return
+	}
+
+	contacts[role] = contact
+}
@@ -0,0 +1,270 @@
+// Package parser is a dependency free WHOIS parser for the ICANN RAA 2013 key/value format,
+// Verisign thin records and the common ccTLD layouts (.uk, .de, .jp, .fr, .nl, .br, .au).
+//
+// Use it for every response or only for some servers or TLDs:
+//
+//	wl := whois.Setup(&whois.Config{Parser: parser.New()})
+//	wl := whois.Setup(&whois.Config{TLDParsers: map[string]whois.Parser{"uk": parser.New()}})
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/chrispassas/whois"
+)
+
+var (
+	// ErrUnrecognized is returned when a response has no domain record in a known layout.
+	ErrUnrecognized = errors.New("Unrecognized WHOIS response")
+)
+
+// Parser implements whois.Parser. The layout is chosen by the server the response came from,
+// falling back to the content of the response.
+type Parser struct{}
+
+// New returns a Parser.
+func New() *Parser {
+	return &Parser{}
+}
+
+// layout parses one response format.
+type layout func(raw string) (info whois.WhoisInfo, err error)
+
+// serverLayouts are the registries with their own response format.
+var serverLayouts = map[string]layout{
+	"whois.nic.uk":             parseUK,
+	"whois.denic.de":           parseDE,
+	"whois.jprs.jp":            parseJP,
+	"whois.nic.fr":             parseFR,
+	"whois.domain-registry.nl": parseNL,
+	"whois.registro.br":        parseBR,
+	"whois.auda.org.au":        parseICANN,
+}
+
+// notFoundMarkers are lower case phrases registries answer with for unregistered domains.
+var notFoundMarkers = []string{
+	"no match for",
+	"no match!!",
+	"not found",
+	"no entries found",
+	"status: free",
+	"this domain name has not been registered",
+	" is free",
+	"no data found",
+	"domain not found",
+}
+
+// Parse parses a raw WHOIS response from server into WhoisInfo.
+func (p *Parser) Parse(raw, server string) (info whois.WhoisInfo, err error) {
+
+	if isNotFound(raw) {
+		err = fmt.Errorf("%w: server:%s", whois.ErrDomainNotFound, server)
+		return info, err
+	}
+
+	parse, ok := serverLayouts[serverHost(server)]
+	if !ok {
+		parse = sniffLayout(raw)
+	}
+
+	if info, err = parse(raw); err != nil {
+		return info, err
+	}
+
+	if info.Domain == nil || info.Domain.Domain == "" {
+		err = fmt.Errorf("%w: server:%s", ErrUnrecognized, server)
+		return whois.WhoisInfo{}, err
+	}
+
+	return info, err
+}
+
+// sniffLayout picks a layout from the content of a response from an unknown server.
+func sniffLayout(raw string) layout {
+	switch {
+	case strings.Contains(raw, "[Domain Name]"):
+		return parseJP
+	case strings.Contains(raw, "nic-hdl-br:"):
+		return parseBR
+	case strings.Contains(raw, "FRNIC"):
+		return parseFR
+	case strings.Contains(raw, "Nserver:") && strings.Contains(raw, "\nDomain: "):
+		return parseDE
+	case strings.Contains(raw, "Domain nameservers:"):
+		return parseNL
+	case strings.Contains(raw, "    Domain name:\n") || strings.Contains(raw, "Relevant dates:"):
+		return parseUK
+	}
+	return parseICANN
+}
+
+// recordFieldPrefixes start the keys of record lines, whose values are data and never an answer.
+var recordFieldPrefixes = []string{
+	"registrant", "admin", "tech", "billing", "registrar", "registry", "reseller", "sponsoring",
+	"domain name", "domain id", "name server", "nserver", "dnssec", "holder", "owner",
+	"organization", "organisation", "name", "email", "e-mail", "address", "phone", "fax",
+	"creation date", "created", "updated date", "expiry date", "expiration date",
+}
+
+// isRecordField reports whether key, lower case, is a field of a domain record.
+func isRecordField(key string) bool {
+	for _, prefix := range recordFieldPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNotFound reports whether the head of a response says the domain is not registered.
+// Only the first lines are checked so that terms of use further down can not match.
+func isNotFound(raw string) bool {
+	lines := strings.SplitN(raw, "\n", 11)
+	if len(lines) > 10 {
+		lines = lines[:10]
+	}
+	for _, line := range lines {
+		line = strings.ToLower(strings.Join(strings.Fields(line), " "))
+		// A record line such as "Registrant Email: not found@example.com" is not an answer
+		if key, _, ok := strings.Cut(line, ":"); ok && isRecordField(strings.TrimSpace(key)) {
+			continue
+		}
+		for _, marker := range notFoundMarkers {
+			if strings.Contains(line, marker) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newDomain fills the name parts of a Domain from its full name.
+func newDomain(name string) *whois.Domain {
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+	domain := &whois.Domain{Domain: name, Punycode: name}
+	if label, ext, ok := strings.Cut(name, "."); ok {
+		domain.Name, domain.Extension = label, ext
+	}
+	return domain
+}
+
+// setDate stores value and its parsed time in the given fields, keeping the first value seen.
+func setDate(value string, text *string, parsed **time.Time) {
+	if *text != "" || value == "" {
+		return
+	}
+	*text = value
+	if t, ok := parseDate(value); ok {
+		*parsed = &t
+	}
+}
+
+// dateLayouts are the date formats seen across registries.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"02-Jan-2006",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	"20060102",
+	"02/01/2006",
+}
+
+// jst is the time zone JPRS dates are given in.
+var jst = time.FixedZone("JST", 9*60*60)
+
+// parseDate parses a registry date. Dates without a zone are UTC, except for "(JST)".
+// Trailing comments such as "#12345" are ignored.
+func parseDate(value string) (t time.Time, ok bool) {
+	value = strings.TrimSpace(value)
+
+	loc := time.UTC
+	if strings.HasSuffix(value, "(JST)") {
+		loc = jst
+	}
+	if i := strings.IndexAny(value, "(#"); i > 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return t, false
+}
+
+// eppStatus drops the ICANN explanation URL from a status ("ok https://icann.org/epp#ok").
+func eppStatus(value string) string {
+	if fields := strings.Fields(value); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// appendUnique appends value unless it is empty or already present.
+func appendUnique(values []string, value string) []string {
+	if value == "" {
+		return values
+	}
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// nameServer lower cases a name server and drops trailing dots and glue addresses.
+func nameServer(value string) string {
+	if fields := strings.Fields(value); len(fields) > 0 {
+		return strings.TrimSuffix(strings.ToLower(fields[0]), ".")
+	}
+	return ""
+}
+
+// dnssecSigned reports whether a DNSSEC value means the zone is signed.
+func dnssecSigned(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "unsigned", "no", "inactive", "unsigned delegation", "false":
+		return false
+	}
+	return true
+}
+
+// keyValue splits a "key: value" line into a lower case key and trimmed value.
+func keyValue(line string) (key, value string, ok bool) {
+	if key, value, ok = strings.Cut(line, ":"); !ok {
+		return key, value, ok
+	}
+	key = strings.ToLower(strings.Join(strings.Fields(key), " "))
+	return key, strings.TrimSpace(value), key != ""
+}
+
+// serverHost returns the lower cased host of a WHOIS server with any port removed.
+func serverHost(server string) string {
+	host, _, err := net.SplitHostPort(server)
+	if err != nil {
+		host = server
+	}
+	return strings.ToLower(host)
+}
+
+// joinNonEmpty joins the non empty values with sep.
+func joinNonEmpty(sep string, values ...string) string {
+	var parts []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chrispassas/whois"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestParse_Golden(t *testing.T) {
+	tests := []struct {
+		name   string
+		server string
+	}{
+		{"icann_registrar", "whois.example-registrar.com"},
+		{"verisign_thin", "whois.verisign-grs.com"},
+		{"uk", "whois.nic.uk"},
+		{"de", "whois.denic.de"},
+		{"jp", "whois.jprs.jp"},
+		{"fr", "whois.nic.fr"},
+		{"nl", "whois.domain-registry.nl"},
+		{"br", "whois.registro.br"},
+		{"au", "whois.auda.org.au"},
+	}
+
+	p := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := os.ReadFile(filepath.Join("testdata", tt.name+".txt"))
+			if err != nil {
+				t.Fatalf("os.ReadFile() error: %v", err)
+			}
+
+			info, err := p.Parse(string(raw), tt.server)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, err := json.MarshalIndent(info, "", "  ")
+			if err != nil {
+				t.Fatalf("json.MarshalIndent() error: %v", err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", tt.name+".json")
+			if *update {
+				if err = os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatalf("os.WriteFile() error: %v", err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("os.ReadFile() error: %v", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("parsed %s does not match %s:\n%s", tt.name, golden, got)
+			}
+
+			// The layout must also be recognized without knowing the server
+			sniffed, err := p.Parse(string(raw), "")
+			if err != nil || sniffed.Domain.Domain != info.Domain.Domain {
+				t.Errorf("unexpected result without server: %+v %v", sniffed.Domain, err)
+			}
+		})
+	}
+}
+
+func TestParse_NotFound(t *testing.T) {
+	tests := []struct {
+		name   string
+		server string
+	}{
+		{"verisign_notfound", "whois.verisign-grs.com"},
+		{"uk_notfound", "whois.nic.uk"},
+		{"de_free", "whois.denic.de"},
+	}
+
+	for _, tt := range tests {
+		raw, err := os.ReadFile(filepath.Join("testdata", tt.name+".txt"))
+		if err != nil {
+			t.Fatalf("os.ReadFile() error: %v", err)
+		}
+		if _, err = New().Parse(string(raw), tt.server); !errors.Is(err, whois.ErrDomainNotFound) {
+			t.Errorf("%s: expected whois.ErrDomainNotFound, got %v", tt.name, err)
+		}
+	}
+
+	// Record values that happen to contain a not found marker
+	for _, raw := range []string{
+		"Domain Name: EXAMPLE.COM\nRegistrant Email: not found@example.com\n",
+		"Domain Name: EXAMPLE.COM\nRegistrar: Domain not found LLC\n",
+	} {
+		info, err := New().Parse(raw, "whois.example.com")
+		if err != nil || info.Domain == nil || info.Domain.Domain != "example.com" {
+			t.Errorf("expected a registered record for %q, got %+v, %v", raw, info, err)
+		}
+	}
+
+	if _, err := New().Parse("%% internal error\n", "whois.example.com"); !errors.Is(err, ErrUnrecognized) {
+		t.Errorf("expected ErrUnrecognized, got %v", err)
+	}
+}
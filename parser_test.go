@@ -0,0 +1,75 @@
+package whois
+
+import (
+	"context"
+	"testing"
+)
+
+// namedParser returns a parser that marks the parsed domain with name.
+func namedParser(name string) Parser {
+	return ParserFunc(func(raw, server string) (WhoisInfo, error) {
+		return WhoisInfo{Domain: &Domain{Domain: name}}, nil
+	})
+}
+
+func TestParserSelection(t *testing.T) {
+	whoisLookup := Setup(&Config{
+		Parser: namedParser("config"),
+		ServerParsers: map[string]Parser{
+			"Whois.Nic.UK": namedParser("server"),
+		},
+		TLDParsers: map[string]Parser{
+			"uk":     namedParser("uk"),
+			".co.uk": namedParser("co.uk"),
+		},
+	})
+
+	tests := []struct {
+		server string
+		domain string
+		want   string
+	}{
+		{"whois.nic.uk:43", "example.co.uk", "server"},
+		{"whois.example.net", "example.co.uk", "co.uk"},
+		{"whois.example.net", "example.org.uk", "uk"},
+		{"whois.example.net", "example.com", "config"},
+	}
+	for _, tt := range tests {
+		info, err := whoisLookup.parse("", tt.server, tt.domain)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if info.Domain.Domain != tt.want {
+			t.Errorf("parse(%v, %v) used parser %v, want %v", tt.server, tt.domain, info.Domain.Domain, tt.want)
+		}
+	}
+
+	if p := Setup(nil).parser("whois.example.net", "example.com"); p != DefaultParser {
+		t.Errorf("expected DefaultParser without configuration")
+	}
+}
+
+func TestGetWhoisWithLocalAddr_CustomParser(t *testing.T) {
+	registry := newTestWhoisServer(t, func(query string) string {
+		return "not a format whoisparser understands\n"
+	})
+
+	var gotServer string
+	whoisLookup := Setup(&Config{
+		TLDParsers: map[string]Parser{
+			"com": ParserFunc(func(raw, server string) (WhoisInfo, error) {
+				gotServer = server
+				return WhoisInfo{Domain: &Domain{Domain: "example.com"}}, nil
+			}),
+		},
+	})
+	whoisLookup.setTLDServerToCache("com", registry)
+
+	result, err := whoisLookup.GetWhoisWithLocalAddr(context.Background(), "example.com", nil)
+	if err != ErrRegistryMissingWhoisServer {
+		t.Fatalf("expected ErrRegistryMissingWhoisServer, got %v", err)
+	}
+	if result.RegistryWhois == nil || result.RegistryWhois.Domain.Domain != "example.com" || gotServer != registry {
+		t.Errorf("expected custom parser to parse the registry response, got %+v", result.RegistryWhois)
+	}
+}
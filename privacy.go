@@ -0,0 +1,181 @@
+package whois
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ContactFields is a set of Contact fields.
+type ContactFields uint16
+
+const (
+	FieldID ContactFields = 1 << iota
+	FieldName
+	FieldOrganization
+	FieldStreet
+	FieldCity
+	FieldProvince
+	FieldPostalCode
+	FieldCountry
+	FieldPhone
+	FieldFax
+	FieldEmail
+)
+
+// contactFields lists the fields checked for redaction with their JSON names.
+var contactFields = []struct {
+	field ContactFields
+	name  string
+	value func(c *Contact) string
+}{
+	{FieldID, "id", func(c *Contact) string { return c.ID }},
+	{FieldName, "name", func(c *Contact) string { return c.Name }},
+	{FieldOrganization, "organization", func(c *Contact) string { return c.Organization }},
+	{FieldStreet, "street", func(c *Contact) string { return c.Street }},
+	{FieldCity, "city", func(c *Contact) string { return c.City }},
+	{FieldProvince, "province", func(c *Contact) string { return c.Province }},
+	{FieldPostalCode, "postal_code", func(c *Contact) string { return c.PostalCode }},
+	{FieldCountry, "country", func(c *Contact) string { return c.Country }},
+	{FieldPhone, "phone", func(c *Contact) string { return c.Phone }},
+	{FieldFax, "fax", func(c *Contact) string { return c.Fax }},
+	{FieldEmail, "email", func(c *Contact) string { return c.Email }},
+}
+
+// Has reports whether field is in the set.
+func (f ContactFields) Has(field ContactFields) bool {
+	return f&field != 0
+}
+
+// Names returns the JSON names of the fields in the set.
+func (f ContactFields) Names() (names []string) {
+	for _, cf := range contactFields {
+		if f.Has(cf.field) {
+			names = append(names, cf.name)
+		}
+	}
+	return names
+}
+
+// MarshalJSON encodes the set as a list of field names.
+func (f ContactFields) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.Names())
+}
+
+// UnmarshalJSON decodes a list of field names, unknown names are ignored.
+func (f *ContactFields) UnmarshalJSON(data []byte) (err error) {
+	var names []string
+	if err = json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	*f = 0
+	for _, name := range names {
+		for _, cf := range contactFields {
+			if cf.name == name {
+				*f |= cf.field
+			}
+		}
+	}
+	return err
+}
+
+// redactionMarkers are lower case phrases registries and registrars put in place of
+// personal data.
+var redactionMarkers = []string{
+	"redacted",
+	"data protected",
+	"not disclosed",
+	"non-public data",
+	"withheld",
+	"gdpr masked",
+	"statutory masking enabled",
+	"private registration",
+	"please query the rdds service",
+	"select request email form",
+	"contact the registrar",
+}
+
+// privacyServices maps what identifies a privacy or proxy service in a name, organization or
+// email, lower case with only letters and digits, to the name of the service. Longer keys go
+// first so that the most specific service wins.
+var privacyServices = []struct {
+	key  string
+	name string
+}{
+	{"whoisprivacyprotectionservice", "Whois Privacy Protection Service"},
+	{"identityprotectionservice", "Identity Protection Service"},
+	{"domainprotectionservices", "Domain Protection Services"},
+	{"withheldforprivacy", "Withheld for Privacy"},
+	{"domainsbyproxy", "Domains By Proxy"},
+	{"contactprivacy", "Contact Privacy Inc."},
+	{"privacyguardian", "PrivacyGuardian.org"},
+	{"proxyprotection", "Proxy Protection LLC"},
+	{"perfectprivacy", "Perfect Privacy"},
+	{"privacyprotect", "PrivacyProtect.org"},
+	{"whoisguard", "WhoisGuard"},
+	{"whoisproxy", "WhoisProxy"},
+}
+
+// isRedacted reports whether a contact value is a redaction notice rather than data.
+func isRedacted(value string) bool {
+	value = strings.ToLower(strings.Join(strings.Fields(value), " "))
+	if value == "" {
+		return false
+	}
+	for _, marker := range redactionMarkers {
+		if strings.Contains(value, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// privacyService returns the name of the privacy or proxy service value belongs to, if any.
+func privacyService(value string) string {
+	key := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, strings.ToLower(value))
+	if key == "" {
+		return ""
+	}
+	for _, service := range privacyServices {
+		if strings.Contains(key, service.key) {
+			return service.name
+		}
+	}
+	return ""
+}
+
+// detectRedaction sets RedactedFields, PrivacyService and Redacted on c. Values are kept as
+// returned by the server.
+func detectRedaction(c *Contact) {
+	if c == nil {
+		return
+	}
+
+	c.RedactedFields = 0
+	for _, cf := range contactFields {
+		if isRedacted(cf.value(c)) {
+			c.RedactedFields |= cf.field
+		}
+	}
+
+	c.PrivacyService = ""
+	for _, value := range []string{c.Organization, c.Name, c.Email} {
+		if c.PrivacyService = privacyService(value); c.PrivacyService != "" {
+			break
+		}
+	}
+
+	c.Redacted = c.RedactedFields != 0 || c.PrivacyService != ""
+}
+
+// detectRedactions runs detectRedaction on the domain contacts. The registrar is a business
+// and is never redacted.
+func detectRedactions(info *WhoisInfo) {
+	for _, c := range []*Contact{info.Registrant, info.Administrative, info.Technical, info.Billing} {
+		detectRedaction(c)
+	}
+}
@@ -0,0 +1,94 @@
+package whois
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDetectRedaction(t *testing.T) {
+	tests := []struct {
+		name     string
+		contact  Contact
+		redacted bool
+		service  string
+		fields   ContactFields
+	}{
+		{
+			name: "redacted for privacy",
+			contact: Contact{
+				ID:           "REDACTED FOR PRIVACY",
+				Name:         "REDACTED FOR PRIVACY",
+				Organization: "Example Inc.",
+				Country:      "US",
+				Email:        "Please query the RDDS service of the Registrar of Record identified in this output for information on how to contact the Registrant",
+			},
+			redacted: true,
+			fields:   FieldID | FieldName | FieldEmail,
+		},
+		{
+			name:     "domains by proxy",
+			contact:  Contact{Name: "Registration Private", Organization: "Domains By Proxy, LLC", Email: "example.com@domainsbyproxy.com"},
+			redacted: true,
+			service:  "Domains By Proxy",
+		},
+		{
+			name:     "withheld for privacy",
+			contact:  Contact{Name: "Redacted for Privacy", Organization: "Privacy service provided by Withheld for Privacy ehf"},
+			redacted: true,
+			service:  "Withheld for Privacy",
+			fields:   FieldName | FieldOrganization,
+		},
+		{
+			name:     "proxy email only",
+			contact:  Contact{Email: "4f1d@whoisguard.com"},
+			redacted: true,
+			service:  "WhoisGuard",
+		},
+		{
+			name:     "data protected",
+			contact:  Contact{Name: "Data Protected", Organization: "Acme Ltd"},
+			redacted: true,
+			fields:   FieldName,
+		},
+		{
+			name:    "public",
+			contact: Contact{Name: "Jane Doe", Organization: "Example Inc.", Email: "jane@example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.contact
+			detectRedaction(&c)
+			if c.Redacted != tt.redacted || c.PrivacyService != tt.service || c.RedactedFields != tt.fields {
+				t.Errorf("got redacted:%v service:%q fields:%v, expected redacted:%v service:%q fields:%v",
+					c.Redacted, c.PrivacyService, c.RedactedFields.Names(), tt.redacted, tt.service, tt.fields.Names())
+			}
+		})
+	}
+}
+
+func TestContactFields_JSON(t *testing.T) {
+	c := Contact{Name: "REDACTED FOR PRIVACY", Email: "redacted@example.com"}
+	detectRedaction(&c)
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"name":"REDACTED FOR PRIVACY","email":"redacted@example.com","redacted":true,"redacted_fields":["name","email"]}`
+	if string(data) != expected {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+
+	var decoded Contact
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded != c {
+		t.Errorf("expected %+v, got %+v", c, decoded)
+	}
+
+	if data, _ = json.Marshal(Contact{Name: "Jane Doe"}); string(data) != `{"name":"Jane Doe"}` {
+		t.Errorf("expected no redaction fields, got %s", data)
+	}
+}
@@ -0,0 +1,129 @@
+package whois
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ServerProfile describes how to talk to a WHOIS server. In the query formats %s is
+// replaced with the object being looked up; empty formats send the object as is.
+type ServerProfile struct {
+	// QueryFormat is used for domain queries, %s is the A-label domain
+	QueryFormat string `json:"query_format,omitempty"`
+	// IPQueryFormat is used for IP address queries
+	IPQueryFormat string `json:"ip_query_format,omitempty"`
+	// ASNQueryFormat is used for ASN queries, %s is the number without the "AS" prefix
+	ASNQueryFormat string `json:"asn_query_format,omitempty"`
+	// Encoding is the character set the server answers in, empty means UTF-8
+	Encoding string `json:"encoding,omitempty"`
+	// Port is used when the server address has no port, 0 means 43
+	Port int `json:"port,omitempty"`
+	// RateLimitBanners are case insensitive substrings that mark a response as throttled,
+	// in addition to the built in banners
+	RateLimitBanners []string `json:"rate_limit_banners,omitempty"`
+	// NotFoundPatterns are case insensitive substrings the registry answers with for
+	// unregistered domains, used by IsAvailable. Runs of spaces match any run of spaces.
+	NotFoundPatterns []string `json:"not_found_patterns,omitempty"`
+}
+
+// defaultServerProfiles are built in profiles for servers that need special query syntax
+// or answer unregistered domains in their own way. Config.ServerProfiles entries replace these per host.
+var defaultServerProfiles = map[string]ServerProfile{
+	// DENIC only returns full records for the "-T dn" type, "ace" accepts A-labels
+	"whois.denic.de": {QueryFormat: "-T dn,ace %s", NotFoundPatterns: []string{"status: free"}},
+	// "domain =" restricts matches to domain records instead of hosts with the same name
+	"whois.verisign-grs.com": {QueryFormat: "domain =%s", NotFoundPatterns: []string{"no match for"}},
+	// "/e" asks JPRS for English output
+	"whois.jprs.jp": {QueryFormat: "%s/e", Encoding: "iso-2022-jp", NotFoundPatterns: []string{"no match!!"}},
+
+	// Registries with their own answer for unregistered domains
+	"whois.nic.uk":                     {NotFoundPatterns: []string{"no match for", "this domain name has not been registered"}},
+	"whois.nic.fr":                     {NotFoundPatterns: []string{"no entries found", "%% not found"}},
+	"whois.domain-registry.nl":         {NotFoundPatterns: []string{"is free"}},
+	"whois.auda.org.au":                {NotFoundPatterns: []string{"not found"}},
+	"whois.registro.br":                {NotFoundPatterns: []string{"no match for"}},
+	"whois.eu":                         {NotFoundPatterns: []string{"status: available"}},
+	"whois.nic.it":                     {NotFoundPatterns: []string{"status: available"}},
+	"whois.dns.pl":                     {NotFoundPatterns: []string{"no information available about domain name"}},
+	"whois.iis.se":                     {NotFoundPatterns: []string{"not found."}},
+	"whois.cira.ca":                    {NotFoundPatterns: []string{"not found:"}},
+	"whois.publicinterestregistry.org": {NotFoundPatterns: []string{"not found"}},
+
+	// ARIN selects record types with a prefix, "+" asks for details
+	"whois.arin.net": {IPQueryFormat: "n + %s", ASNQueryFormat: "a + %s"},
+	// The RIPE database family: skip contact lookups, return unfiltered objects
+	"whois.ripe.net":    {IPQueryFormat: "-r -B %s", ASNQueryFormat: "-r -B AS%s"},
+	"whois.apnic.net":   {IPQueryFormat: "-r -B %s", ASNQueryFormat: "-r -B AS%s"},
+	"whois.afrinic.net": {IPQueryFormat: "-r -B %s", ASNQueryFormat: "-r -B AS%s"},
+}
+
+// queryKind is the type of object sent to a WHOIS server.
+type queryKind int
+
+const (
+	queryDomain queryKind = iota
+	queryIP
+	queryASN
+)
+
+// query formats object for the server.
+func (p ServerProfile) query(kind queryKind, object string) string {
+	format := p.QueryFormat
+	switch kind {
+	case queryIP:
+		format = p.IPQueryFormat
+	case queryASN:
+		format = p.ASNQueryFormat
+		if format == "" {
+			format = "AS%s"
+		}
+	}
+
+	if format == "" {
+		return object
+	}
+
+	return strings.ReplaceAll(format, "%s", object)
+}
+
+// mergeServerProfiles returns the built in profiles with overrides applied, keyed by lower case host.
+func mergeServerProfiles(overrides map[string]ServerProfile) map[string]ServerProfile {
+	profiles := make(map[string]ServerProfile, len(defaultServerProfiles)+len(overrides))
+	for host, profile := range defaultServerProfiles {
+		profiles[host] = profile
+	}
+	for host, profile := range overrides {
+		profiles[serverHost(host)] = profile
+	}
+	return profiles
+}
+
+// serverProfile returns the profile for a WHOIS server, or an empty profile if none is known.
+func (wl *WhoisLookup) serverProfile(whoisServer string) ServerProfile {
+	return wl.serverProfiles[serverHost(whoisServer)]
+}
+
+// whoisAddress returns host:port for a WHOIS server. A port in the server wins over
+// the profile port, which wins over the default port 43.
+func (wl *WhoisLookup) whoisAddress(whoisServer string) string {
+	if _, _, err := net.SplitHostPort(whoisServer); err == nil {
+		return whoisServer
+	}
+
+	port := 43
+	if profile := wl.serverProfile(whoisServer); profile.Port != 0 {
+		port = profile.Port
+	}
+
+	return net.JoinHostPort(whoisServer, strconv.Itoa(port))
+}
+
+// serverHost returns the lower cased host of a WHOIS server with any port removed.
+func serverHost(whoisServer string) string {
+	host, _, err := net.SplitHostPort(whoisServer)
+	if err != nil {
+		host = whoisServer
+	}
+	return strings.ToLower(host)
+}
@@ -0,0 +1,80 @@
+package whois
+
+import (
+	"context"
+	"testing"
+)
+
+func TestServerProfileQuery(t *testing.T) {
+	whoisLookup := Setup(nil)
+
+	tests := []struct {
+		server string
+		kind   queryKind
+		object string
+		want   string
+	}{
+		{"whois.denic.de", queryDomain, "example.de", "-T dn,ace example.de"},
+		{"WHOIS.VERISIGN-GRS.COM", queryDomain, "example.com", "domain =example.com"},
+		{"whois.jprs.jp:43", queryDomain, "example.jp", "example.jp/e"},
+		{"whois.nic.io", queryDomain, "example.io", "example.io"},
+		{"whois.arin.net", queryIP, "8.8.8.8", "n + 8.8.8.8"},
+		{"whois.ripe.net", queryIP, "193.0.0.1", "-r -B 193.0.0.1"},
+		{"whois.lacnic.net", queryIP, "200.3.12.1", "200.3.12.1"},
+		{"whois.arin.net", queryASN, "13335", "a + 13335"},
+		{"whois.apnic.net", queryASN, "4608", "-r -B AS4608"},
+		{"whois.lacnic.net", queryASN, "28000", "AS28000"},
+	}
+	for _, tt := range tests {
+		if got := whoisLookup.serverProfile(tt.server).query(tt.kind, tt.object); got != tt.want {
+			t.Errorf("query(%v, %v) = %v, want %v", tt.server, tt.object, got, tt.want)
+		}
+	}
+}
+
+func TestServerProfileOverride(t *testing.T) {
+	queries := make(chan string, 1)
+	server := newTestWhoisServer(t, func(q string) string {
+		queries <- q
+		return "Domain Name: EXAMPLE.COM\n"
+	})
+
+	whoisLookup := Setup(&Config{
+		ServerProfiles: map[string]ServerProfile{
+			"Whois.Verisign-GRS.com": {QueryFormat: "=%s"},
+		},
+	})
+
+	if got := whoisLookup.serverProfile("whois.verisign-grs.com").query(queryDomain, "example.com"); got != "=example.com" {
+		t.Errorf("expected override to replace the built in profile, got %v", got)
+	}
+	if got := whoisLookup.serverProfile("whois.denic.de").query(queryDomain, "example.de"); got != "-T dn,ace example.de" {
+		t.Errorf("expected built in profiles to remain, got %v", got)
+	}
+
+	whoisLookup.serverProfiles["127.0.0.1"] = ServerProfile{QueryFormat: "custom %s"}
+	if _, err := whoisLookup.queryWhois(context.Background(), "example.com", server, whoisLookup.config.DefaultTimeout, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if query := <-queries; query != "custom example.com" {
+		t.Errorf("expected profile query to be sent, got %v", query)
+	}
+}
+
+func TestWhoisAddress(t *testing.T) {
+	whoisLookup := Setup(&Config{
+		ServerProfiles: map[string]ServerProfile{"whois.example.net": {Port: 4343}},
+	})
+
+	tests := map[string]string{
+		"whois.nic.io":           "whois.nic.io:43",
+		"whois.example.net":      "whois.example.net:4343",
+		"whois.example.net:4444": "whois.example.net:4444",
+		"127.0.0.1:4300":         "127.0.0.1:4300",
+	}
+	for server, want := range tests {
+		if got := whoisLookup.whoisAddress(server); got != want {
+			t.Errorf("whoisAddress(%v) = %v, want %v", server, got, want)
+		}
+	}
+}
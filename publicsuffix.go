@@ -0,0 +1,221 @@
+package whois
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+var (
+	// ErrPublicSuffixList is returned when a Public Suffix List can not be loaded.
+	ErrPublicSuffixList = errors.New("Failed to load public suffix list")
+)
+
+// suffixWhoisServers are multi-label public suffixes with their own WHOIS server.
+// Every other suffix uses the WHOIS server of its TLD.
+var suffixWhoisServers = map[string]string{
+	"ac.uk":   "whois.ja.net",
+	"gov.uk":  "whois.ja.net",
+	"br.com":  "whois.centralnic.com",
+	"cn.com":  "whois.centralnic.com",
+	"de.com":  "whois.centralnic.com",
+	"eu.com":  "whois.centralnic.com",
+	"gb.net":  "whois.centralnic.com",
+	"jpn.com": "whois.centralnic.com",
+	"ru.com":  "whois.centralnic.com",
+	"sa.com":  "whois.centralnic.com",
+	"uk.com":  "whois.centralnic.com",
+	"uk.net":  "whois.centralnic.com",
+	"us.com":  "whois.centralnic.com",
+	"za.com":  "whois.centralnic.com",
+}
+
+// domainParts is a domain split on its public suffix.
+type domainParts struct {
+	// TLD is the last label ("uk")
+	TLD string
+	// ETLD is the public suffix the domain is registered under ("co.uk")
+	ETLD string
+	// RegistrableDomain is the A-label name sent to WHOIS servers ("example.co.uk")
+	RegistrableDomain string
+	// ASCII and Unicode are the full input in A-label and U-label form
+	ASCII   string
+	Unicode string
+}
+
+// suffixList is a Public Suffix List loaded at runtime. When no list is loaded the
+// table compiled into golang.org/x/net/publicsuffix is used.
+type suffixList struct {
+	m sync.RWMutex
+	// rules maps each rule to whether it is in the ICANN section of the list
+	rules map[string]bool
+}
+
+// RefreshPublicSuffixList downloads the Public Suffix List from Config.PublicSuffixListURL
+// and uses it instead of the embedded copy.
+func (wl *WhoisLookup) RefreshPublicSuffixList(ctx context.Context) (err error) {
+
+	var body []byte
+	if body, err = wl.httpGet(ctx, wl.config.PublicSuffixListURL, nil); err != nil {
+		err = errors.Join(ErrPublicSuffixList, fmt.Errorf("httpGet() url:%s error:%w", wl.config.PublicSuffixListURL, err))
+		return err
+	}
+
+	return wl.LoadPublicSuffixList(strings.NewReader(string(body)))
+}
+
+// LoadPublicSuffixList replaces the embedded Public Suffix List with one read from r
+// in the public_suffix_list.dat format.
+func (wl *WhoisLookup) LoadPublicSuffixList(r io.Reader) (err error) {
+
+	rules := make(map[string]bool)
+	icann := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.Contains(line, "===BEGIN ICANN DOMAINS==="):
+			icann = true
+			continue
+		case strings.Contains(line, "===END ICANN DOMAINS==="):
+			icann = false
+			continue
+		case line == "" || strings.HasPrefix(line, "//"):
+			continue
+		}
+
+		// Rules end at the first whitespace and are matched in A-label form
+		rule := strings.Fields(line)[0]
+		prefix := ""
+		for _, p := range []string{"!", "*."} {
+			if name, ok := strings.CutPrefix(rule, p); ok {
+				prefix, rule = p, name
+			}
+		}
+		if ascii, err := idna.ToASCII(rule); err == nil {
+			rule = ascii
+		}
+		rules[prefix+strings.ToLower(rule)] = icann
+	}
+
+	if err = scanner.Err(); err != nil {
+		err = errors.Join(ErrPublicSuffixList, fmt.Errorf("error reading public suffix list: %w", err))
+		return err
+	}
+	if len(rules) == 0 {
+		err = fmt.Errorf("%w: no rules", ErrPublicSuffixList)
+		return err
+	}
+
+	wl.suffixList.m.Lock()
+	wl.suffixList.rules = rules
+	wl.suffixList.m.Unlock()
+
+	return err
+}
+
+// publicSuffix returns the public suffix of domain and whether it is an ICANN (as
+// opposed to a private) suffix.
+func (wl *WhoisLookup) publicSuffix(domain string) (suffix string, icann bool) {
+	wl.suffixList.m.RLock()
+	defer wl.suffixList.m.RUnlock()
+
+	if wl.suffixList.rules == nil {
+		return publicsuffix.PublicSuffix(domain)
+	}
+
+	return matchSuffixRules(wl.suffixList.rules, domain)
+}
+
+// matchSuffixRules implements the Public Suffix List algorithm, with "*" as the default rule.
+// Rules are keyed by their text: "name", "*.name" for wildcards and "!name" for exceptions.
+func matchSuffixRules(rules map[string]bool, domain string) (suffix string, icann bool) {
+	labels := strings.Split(domain, ".")
+
+	// The first match walking from the full domain down is the longest, most specific rule
+	for i := range labels {
+		name := strings.Join(labels[i:], ".")
+		if icann, ok := rules["!"+name]; ok {
+			return strings.Join(labels[i+1:], "."), icann
+		}
+		if icann, ok := rules[name]; ok {
+			return name, icann
+		}
+		if i+1 < len(labels) {
+			if icann, ok := rules["*."+strings.Join(labels[i+1:], ".")]; ok {
+				return name, icann
+			}
+		}
+	}
+
+	return labels[len(labels)-1], false
+}
+
+// splitDomain normalizes domain and splits it into TLD, public suffix and registrable domain.
+// Private suffixes (github.io) are not registries, so the ICANN suffix is used unless a
+// WHOIS server is known for the private suffix itself (CentralNic's br.com).
+func (wl *WhoisLookup) splitDomain(domain string) (parts domainParts, err error) {
+
+	if net.ParseIP(strings.TrimSpace(domain)) != nil {
+		err = &InvalidDomainError{Domain: domain, Reason: "IP address"}
+		return parts, err
+	}
+
+	if parts.ASCII, parts.Unicode, err = normalizeDomain(domain); err != nil {
+		return parts, err
+	}
+
+	labels := strings.Split(parts.ASCII, ".")
+	if len(labels) < 2 {
+		err = &InvalidDomainError{Domain: domain, Reason: "missing TLD"}
+		return parts, err
+	}
+	parts.TLD = labels[len(labels)-1]
+
+	domain = parts.ASCII
+	suffix, icann := wl.publicSuffix(domain)
+	for !icann && suffixWhoisServers[suffix] == "" {
+		i := strings.Index(suffix, ".")
+		if i < 0 {
+			break
+		}
+		suffix, icann = wl.publicSuffix(suffix[i+1:])
+	}
+	parts.ETLD = suffix
+
+	if domain == suffix || !strings.HasSuffix(domain, "."+suffix) {
+		err = &InvalidDomainError{Domain: domain, Reason: "public suffix " + suffix}
+		return parts, err
+	}
+
+	rest := strings.TrimSuffix(domain, "."+suffix)
+	parts.RegistrableDomain = rest[strings.LastIndex(rest, ".")+1:] + "." + suffix
+
+	return parts, err
+}
+
+// getWhoisServerForSuffix returns the WHOIS server for a public suffix. A suffix in
+// Config.TLDServers uses its first server, multi-label suffixes with a dedicated server use it,
+// everything else uses the TLD server.
+func (wl *WhoisLookup) getWhoisServerForSuffix(ctx context.Context, suffix string, localAddr *net.TCPAddr) (whoisServer string, err error) {
+	if whoisServer, ok := wl.overrideServer(suffix); ok {
+		return whoisServer, err
+	}
+	if whoisServer = suffixWhoisServers[suffix]; whoisServer != "" {
+		return whoisServer, err
+	}
+
+	tld := suffix[strings.LastIndex(suffix, ".")+1:]
+
+	return wl.getWhoisServerForTLD(ctx, tld, localAddr)
+}
@@ -0,0 +1,83 @@
+package whois
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitDomain(t *testing.T) {
+	whoisLookup := Setup(nil)
+
+	tests := []struct {
+		domain string
+		want   domainParts
+	}{
+		{"example.com", domainParts{TLD: "com", ETLD: "com", RegistrableDomain: "example.com"}},
+		{"WWW.Example.co.uk.", domainParts{TLD: "uk", ETLD: "co.uk", RegistrableDomain: "example.co.uk"}},
+		{"foo.bar.github.io", domainParts{TLD: "io", ETLD: "io", RegistrableDomain: "github.io"}},
+		{"www.example.br.com", domainParts{TLD: "com", ETLD: "br.com", RegistrableDomain: "example.br.com"}},
+		{"a.b.example.unknowntld", domainParts{TLD: "unknowntld", ETLD: "unknowntld", RegistrableDomain: "example.unknowntld"}},
+	}
+	for _, tt := range tests {
+		got, err := whoisLookup.splitDomain(tt.domain)
+		if err != nil {
+			t.Errorf("splitDomain(%v) unexpected error: %v", tt.domain, err)
+			continue
+		}
+		if got.TLD != tt.want.TLD || got.ETLD != tt.want.ETLD || got.RegistrableDomain != tt.want.RegistrableDomain {
+			t.Errorf("splitDomain(%v) = %+v, want %+v", tt.domain, got, tt.want)
+		}
+	}
+
+	for _, domain := range []string{"com", "co.uk", "example..com", "127.0.0.1", ""} {
+		if _, err := whoisLookup.splitDomain(domain); err == nil {
+			t.Errorf("splitDomain(%v) expected an error", domain)
+		}
+	}
+}
+
+func TestLoadPublicSuffixList(t *testing.T) {
+	whoisLookup := Setup(nil)
+
+	list := `// ===BEGIN ICANN DOMAINS===
+com
+uk
+co.uk
+*.ck
+!www.ck
+// ===END ICANN DOMAINS===
+// ===BEGIN PRIVATE DOMAINS===
+blogspot.co.uk
+// ===END PRIVATE DOMAINS===
+`
+	if err := whoisLookup.LoadPublicSuffixList(strings.NewReader(list)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		domain string
+		suffix string
+		icann  bool
+	}{
+		{"a.example.co.uk", "co.uk", true},
+		{"example.blogspot.co.uk", "blogspot.co.uk", false},
+		{"a.example.ck", "example.ck", true},
+		{"www.ck", "ck", true},
+		{"example.zz", "zz", false},
+	}
+	for _, tt := range tests {
+		suffix, icann := whoisLookup.publicSuffix(tt.domain)
+		if suffix != tt.suffix || icann != tt.icann {
+			t.Errorf("publicSuffix(%v) = %v, %v, want %v, %v", tt.domain, suffix, icann, tt.suffix, tt.icann)
+		}
+	}
+
+	parts, err := whoisLookup.splitDomain("www.example.blogspot.co.uk")
+	if err != nil || parts.RegistrableDomain != "blogspot.co.uk" {
+		t.Errorf("expected private suffix to fall back to the ICANN suffix, got %+v %v", parts, err)
+	}
+
+	if err := whoisLookup.LoadPublicSuffixList(strings.NewReader("// only comments\n")); err == nil {
+		t.Error("expected an error for an empty list")
+	}
+}
@@ -0,0 +1,152 @@
+package whois
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimit throttles queries to a single WHOIS host. Zero and negative values mean unlimited,
+// except that Setup replaces a zero Config.RateLimit by the default.
+type RateLimit struct {
+	// QueriesPerSecond is the token bucket refill rate
+	QueriesPerSecond float64 `json:"queries_per_second"`
+	// Burst is the token bucket size, at least 1 when QueriesPerSecond is set
+	Burst int `json:"burst"`
+	// MaxConcurrent caps the number of open connections to the host
+	MaxConcurrent int `json:"max_concurrent"`
+}
+
+// hostLimiter is a token bucket plus a connection semaphore for one host.
+type hostLimiter struct {
+	m       sync.Mutex
+	limit   RateLimit
+	tokens  float64
+	last    time.Time
+	waiting int
+	slots   chan struct{}
+}
+
+func newHostLimiter(limit RateLimit) *hostLimiter {
+	if limit.QueriesPerSecond > 0 && limit.Burst < 1 {
+		limit.Burst = 1
+	}
+
+	l := &hostLimiter{
+		limit:  limit,
+		tokens: float64(limit.Burst),
+		last:   time.Now(),
+	}
+	if limit.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, limit.MaxConcurrent)
+	}
+	return l
+}
+
+// acquire blocks until a connection slot and a token are available or ctx is done.
+// release must be called once the connection is closed.
+func (l *hostLimiter) acquire(ctx context.Context) (release func(), err error) {
+	l.m.Lock()
+	l.waiting++
+	l.m.Unlock()
+	defer func() {
+		l.m.Lock()
+		l.waiting--
+		l.m.Unlock()
+	}()
+
+	release = func() {}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+			release = func() { <-l.slots }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if err = l.wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+
+	return release, nil
+}
+
+// wait takes one token from the bucket, sleeping until one is available.
+func (l *hostLimiter) wait(ctx context.Context) (err error) {
+	if l.limit.QueriesPerSecond <= 0 {
+		return nil
+	}
+
+	l.m.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.limit.QueriesPerSecond
+	if l.tokens > float64(l.limit.Burst) {
+		l.tokens = float64(l.limit.Burst)
+	}
+	l.last = now
+
+	// Reserve the token now, a negative balance is the queue of waiters ahead of us
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.limit.QueriesPerSecond * float64(time.Second))
+	l.m.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Hand the reserved token back
+		l.m.Lock()
+		l.tokens++
+		l.m.Unlock()
+		return ctx.Err()
+	}
+}
+
+// limiter returns the limiter for a WHOIS host, creating it from Config.RateLimits or Config.RateLimit.
+func (wl *WhoisLookup) limiter(host string) *hostLimiter {
+	host = serverHost(host)
+
+	wl.limitersMutex.Lock()
+	defer wl.limitersMutex.Unlock()
+
+	if l, ok := wl.limiters[host]; ok {
+		return l
+	}
+
+	limit := wl.config.RateLimit
+	for h, hostLimit := range wl.config.RateLimits {
+		if serverHost(h) == host {
+			limit = hostLimit
+			break
+		}
+	}
+
+	l := newHostLimiter(limit)
+	wl.limiters[host] = l
+	return l
+}
+
+// QueueDepth returns the number of queries currently waiting for a rate limit or
+// connection slot, per WHOIS host. Hosts that have been queried but have nothing
+// waiting are reported with 0.
+func (wl *WhoisLookup) QueueDepth() (depth map[string]int) {
+	wl.limitersMutex.Lock()
+	defer wl.limitersMutex.Unlock()
+
+	depth = make(map[string]int, len(wl.limiters))
+	for host, l := range wl.limiters {
+		l.m.Lock()
+		depth[host] = l.waiting
+		l.m.Unlock()
+	}
+	return depth
+}
@@ -0,0 +1,112 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// noRateLimit disables the default rate limit for tests that query a local server many times.
+var noRateLimit = RateLimit{QueriesPerSecond: -1, MaxConcurrent: -1}
+
+func TestRateLimitDefault(t *testing.T) {
+	// A zero RateLimit is replaced by the default, as it is for Setup(nil)
+	whoisLookup := Setup(&Config{DefaultTimeout: time.Second})
+	if whoisLookup.config.RateLimit != DefaultConfig().RateLimit {
+		t.Errorf("expected the default rate limit, got %+v", whoisLookup.config.RateLimit)
+	}
+
+	// Negative values disable it
+	whoisLookup = Setup(&Config{RateLimit: noRateLimit})
+	l := whoisLookup.limiter("whois.example.com")
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		release, err := l.acquire(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer release()
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("expected no throttling, took %v", elapsed)
+	}
+}
+
+func TestHostLimiter_TokenBucket(t *testing.T) {
+	l := newHostLimiter(RateLimit{QueriesPerSecond: 20, Burst: 2})
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		release, err := l.acquire(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		release()
+	}
+
+	// Two queries come out of the burst, the other two wait 50ms each
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected the bucket to throttle, took %v", elapsed)
+	}
+}
+
+func TestHostLimiter_MaxConcurrent(t *testing.T) {
+	l := newHostLimiter(RateLimit{MaxConcurrent: 1})
+
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err = l.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the second connection to block until the deadline, got %v", err)
+	}
+
+	release()
+	if release, err = l.acquire(context.Background()); err != nil {
+		t.Fatalf("expected a free slot after release, got %v", err)
+	}
+	release()
+}
+
+func TestQueueDepth(t *testing.T) {
+	whoisLookup := Setup(&Config{
+		RateLimits: map[string]RateLimit{"Whois.Example.com": {MaxConcurrent: 1}},
+	})
+
+	l := whoisLookup.limiter("whois.example.com:43")
+	if l.limit.MaxConcurrent != 1 {
+		t.Fatalf("expected the per host limit, got %+v", l.limit)
+	}
+	if other := whoisLookup.limiter("whois.example.net"); other.limit != whoisLookup.config.RateLimit {
+		t.Errorf("expected the default limit, got %+v", other.limit)
+	}
+
+	release, _ := l.acquire(context.Background())
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		l.acquire(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for whoisLookup.QueueDepth()["whois.example.com"] != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected one waiting query, got %v", whoisLookup.QueueDepth())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	<-done
+	if depth := whoisLookup.QueueDepth()["whois.example.com"]; depth != 0 {
+		t.Errorf("expected an empty queue after cancel, got %v", depth)
+	}
+}
@@ -0,0 +1,702 @@
+package whois
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrRDAPServerNotFound is returned when the IANA bootstrap file has no RDAP service for a TLD.
+	ErrRDAPServerNotFound = errors.New("RDAP server not found for TLD")
+	// ErrRDAPStatus is returned when an RDAP server answers with a non 200 HTTP status.
+	ErrRDAPStatus = errors.New("Unexpected RDAP response status")
+)
+
+// rdapStatusError is returned for non 200 HTTP statuses, it matches ErrRDAPStatus with errors.Is.
+type rdapStatusError struct {
+	StatusCode int
+}
+
+func (e *rdapStatusError) Error() string {
+	return fmt.Sprintf("%s: %d", ErrRDAPStatus, e.StatusCode)
+}
+
+func (e *rdapStatusError) Unwrap() error {
+	return ErrRDAPStatus
+}
+
+const rdapContentType = "application/rdap+json"
+
+type rdapBootstrapCache struct {
+	m           sync.RWMutex
+	services    map[string][]string
+	lastUpdated time.Time
+}
+
+// rdapBootstrap is the IANA RDAP bootstrap file format (RFC 9224).
+type rdapBootstrap struct {
+	Version     string       `json:"version"`
+	Publication string       `json:"publication"`
+	Services    [][][]string `json:"services"`
+}
+
+type rdapDomain struct {
+	ObjectClassName string           `json:"objectClassName"`
+	Handle          string           `json:"handle"`
+	LDHName         string           `json:"ldhName"`
+	UnicodeName     string           `json:"unicodeName"`
+	Status          []string         `json:"status"`
+	Nameservers     []rdapNameserver `json:"nameservers"`
+	SecureDNS       *rdapSecureDNS   `json:"secureDNS"`
+	Events          []rdapEvent      `json:"events"`
+	Entities        []rdapEntity     `json:"entities"`
+	Links           []rdapLink       `json:"links"`
+	Notices         []rdapNotice     `json:"notices"`
+	Port43          string           `json:"port43"`
+}
+
+type rdapNameserver struct {
+	LDHName string `json:"ldhName"`
+}
+
+type rdapSecureDNS struct {
+	DelegationSigned bool         `json:"delegationSigned"`
+	DSData           []rdapDSData `json:"dsData"`
+}
+
+type rdapDSData struct {
+	KeyTag     uint16 `json:"keyTag"`
+	Algorithm  uint8  `json:"algorithm"`
+	DigestType uint8  `json:"digestType"`
+	Digest     string `json:"digest"`
+}
+
+type rdapNotice struct {
+	Title       string     `json:"title"`
+	Description []string   `json:"description"`
+	Links       []rdapLink `json:"links"`
+}
+
+type rdapEvent struct {
+	EventAction string `json:"eventAction"`
+	EventDate   string `json:"eventDate"`
+}
+
+type rdapLink struct {
+	Value string `json:"value"`
+	Rel   string `json:"rel"`
+	Href  string `json:"href"`
+	Type  string `json:"type"`
+}
+
+type rdapPublicID struct {
+	Type       string `json:"type"`
+	Identifier string `json:"identifier"`
+}
+
+type rdapEntity struct {
+	Handle     string            `json:"handle"`
+	Roles      []string          `json:"roles"`
+	VCardArray []json.RawMessage `json:"vcardArray"`
+	PublicIDs  []rdapPublicID    `json:"publicIds"`
+	Entities   []rdapEntity      `json:"entities"`
+	Links      []rdapLink        `json:"links"`
+}
+
+// GetRDAP returns the WHOIS information for the specified domain using RDAP.
+// The RDAP base URL is resolved from the IANA bootstrap file. If the registry response
+// contains a "related" link to the registrar RDAP service it is followed and the registrar
+// data is returned, mirroring GetRegistrarWhois. The registry data is returned when the
+// registrar RDAP service fails.
+func (wl *WhoisLookup) GetRDAP(ctx context.Context, domain string) (whoisInfo WhoisInfo, rdapRaw string, err error) {
+	return wl.GetRDAPWithLocalAddr(ctx, domain, nil)
+}
+
+// GetRDAPWithLocalAddr returns the WHOIS information for the specified domain using RDAP.
+func (wl *WhoisLookup) GetRDAPWithLocalAddr(ctx context.Context, domain string, localAddr *net.TCPAddr) (whoisInfo WhoisInfo, rdapRaw string, err error) {
+
+	var lookup rdapLookup
+	if lookup, err = wl.lookupRDAP(ctx, domain, localAddr); err != nil {
+		return whoisInfo, lookup.rawForInfo(), err
+	}
+
+	if lookup.registrar != nil {
+		return *lookup.registrar, lookup.registrarRaw, err
+	}
+
+	return *lookup.registry, lookup.registryRaw, err
+}
+
+// lookupRDAPResult fills result using the RDAP registry and registrar hops.
+func (wl *WhoisLookup) lookupRDAPResult(ctx context.Context, result *Result, localAddr *net.TCPAddr) (err error) {
+
+	var lookup rdapLookup
+	lookup, err = wl.lookupRDAP(ctx, result.Domain, localAddr)
+
+	result.setDomainParts(lookup.parts)
+	result.RegistryRDAPURL = lookup.registryURL
+	result.RegistryRDAPRaw = lookup.registryRaw
+	result.RegistryWhois = lookup.registry
+	result.RegistrarRDAPURL = lookup.registrarURL
+	result.RegistrarRDAPRaw = lookup.registrarRaw
+	result.RegistrarWhois = lookup.registrar
+
+	if lookup.registryURL != "" {
+		result.Hops = append(result.Hops, Hop{Server: lookup.registryURL, Raw: lookup.registryRaw, Info: lookup.registry, Latency: lookup.registryLatency})
+	}
+	if lookup.registrarURL != "" {
+		hop := Hop{Server: lookup.registrarURL, Raw: lookup.registrarRaw, Info: lookup.registrar, Latency: lookup.registrarLatency}
+		if lookup.registrarErr != nil {
+			hop.Error = lookup.registrarErr.Error()
+		}
+		result.Hops = append(result.Hops, hop)
+	}
+	if err != nil && len(result.Hops) > 0 {
+		result.Hops[len(result.Hops)-1].Error = err.Error()
+	}
+
+	return err
+}
+
+// rdapLookup holds both hops of an RDAP lookup.
+type rdapLookup struct {
+	parts            domainParts
+	registryURL      string
+	registry         *WhoisInfo
+	registryRaw      string
+	registryLatency  time.Duration
+	registrarURL     string
+	registrar        *WhoisInfo
+	registrarRaw     string
+	registrarLatency time.Duration
+	// registrarErr is set when the registrar hop failed, the registry record is kept
+	registrarErr error
+}
+
+func (l rdapLookup) rawForInfo() string {
+	if l.registrarRaw != "" {
+		return l.registrarRaw
+	}
+	return l.registryRaw
+}
+
+// lookupRDAP resolves the RDAP server for the domain, queries it and follows the registrar related link.
+// Only a failure of the registry hop is returned, a failed registrar hop is kept in registrarErr.
+func (wl *WhoisLookup) lookupRDAP(ctx context.Context, domain string, localAddr *net.TCPAddr) (lookup rdapLookup, err error) {
+
+	if lookup.parts, err = wl.splitDomain(domain); err != nil {
+		return lookup, err
+	}
+
+	var baseURL string
+	if baseURL, err = wl.getRDAPServerForTLD(ctx, lookup.parts.TLD, localAddr); err != nil {
+		err = fmt.Errorf("getRDAPServerForTLD() error:%w", err)
+		return lookup, err
+	}
+
+	lookup.registryURL = baseURL + "domain/" + lookup.parts.RegistrableDomain
+
+	var registry rdapDomain
+	start := time.Now()
+	registry, lookup.registryRaw, err = wl.queryRDAP(ctx, lookup.registryURL, localAddr)
+	lookup.registryLatency = time.Since(start)
+	if err != nil {
+		// RFC 9083: a registry answers 404 for domains it has no record of
+		var statusErr *rdapStatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			err = fmt.Errorf("%w: %w", ErrDomainNotFound, err)
+		}
+		err = fmt.Errorf("queryRDAP() url:%s error:%w", lookup.registryURL, err)
+		return lookup, err
+	}
+	registryInfo := convertRDAPDomain(registry)
+	lookup.registry = &registryInfo
+
+	// Follow the registrar RDAP service if the registry points at one
+	if lookup.registrarURL = registry.relatedURL(lookup.registryURL); lookup.registrarURL == "" {
+		return lookup, err
+	}
+
+	var registrar rdapDomain
+	start = time.Now()
+	registrar, lookup.registrarRaw, lookup.registrarErr = wl.queryRDAP(ctx, lookup.registrarURL, localAddr)
+	lookup.registrarLatency = time.Since(start)
+	if lookup.registrarErr != nil {
+		lookup.registrarErr = fmt.Errorf("queryRDAP() url:%s error:%w", lookup.registrarURL, lookup.registrarErr)
+		return lookup, err
+	}
+	registrarInfo := convertRDAPDomain(registrar)
+	lookup.registrar = &registrarInfo
+
+	return lookup, err
+}
+
+// relatedURL returns the registrar RDAP URL from the "related" links of a registry response.
+func (d rdapDomain) relatedURL(self string) string {
+	for _, link := range d.Links {
+		if link.Rel != "related" || link.Href == "" || link.Href == self {
+			continue
+		}
+		if link.Type != "" && !strings.HasPrefix(link.Type, rdapContentType) {
+			continue
+		}
+		return link.Href
+	}
+	return ""
+}
+
+// queryRDAP fetches and decodes an RDAP domain object.
+func (wl *WhoisLookup) queryRDAP(ctx context.Context, url string, localAddr *net.TCPAddr) (domain rdapDomain, raw string, err error) {
+
+	var body []byte
+	if body, err = wl.httpGet(ctx, url, localAddr); err != nil {
+		return domain, raw, err
+	}
+	raw = string(body)
+
+	if err = json.Unmarshal(body, &domain); err != nil {
+		err = fmt.Errorf("json.Unmarshal() error:%w", err)
+		return domain, raw, err
+	}
+
+	return domain, raw, err
+}
+
+// httpGet performs an RDAP HTTP GET request and returns the body.
+func (wl *WhoisLookup) httpGet(ctx context.Context, url string, localAddr *net.TCPAddr) (body []byte, err error) {
+
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, nil); err != nil {
+		err = fmt.Errorf("http.NewRequestWithContext() error:%w", err)
+		return body, err
+	}
+	req.Header.Set("Accept", rdapContentType+", application/json")
+
+	var resp *http.Response
+	if resp, err = wl.httpClient(localAddr).Do(req); err != nil {
+		err = fmt.Errorf("client.Do() error:%w", err)
+		return body, err
+	}
+	defer resp.Body.Close()
+
+	if body, err = io.ReadAll(io.LimitReader(resp.Body, wl.config.MaxResponseBytes+1)); err != nil {
+		err = fmt.Errorf("io.ReadAll() error:%w", err)
+		return body, err
+	}
+
+	// A cut down JSON document is useless, so RDAP responses are never truncated
+	if int64(len(body)) > wl.config.MaxResponseBytes {
+		err = fmt.Errorf("%w: url:%s max bytes:%d", ErrResponseTooLarge, url, wl.config.MaxResponseBytes)
+		return body, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		err = rdapRateLimited(url, resp)
+		return body, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		err = &rdapStatusError{StatusCode: resp.StatusCode}
+		return body, err
+	}
+
+	return body, err
+}
+
+// httpClient returns the HTTP client used for RDAP. A configured client always wins,
+// otherwise a client dialing from the requested local address is used.
+func (wl *WhoisLookup) httpClient(localAddr *net.TCPAddr) *http.Client {
+	if wl.config.HTTPClient != nil {
+		return wl.config.HTTPClient
+	}
+
+	if localAddr == nil {
+		return wl.rdapClient
+	}
+
+	return newHTTPClient(wl.config.DefaultTimeout, func() *net.TCPAddr { return localAddr })
+}
+
+func newHTTPClient(timeout time.Duration, localAddr func() *net.TCPAddr) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialer := net.Dialer{
+			Timeout:   timeout,
+			LocalAddr: localAddr(),
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
+
+// getRDAPServerForTLD returns the RDAP base URL for the TLD from the IANA bootstrap file.
+// The base URL always ends with a slash.
+func (wl *WhoisLookup) getRDAPServerForTLD(ctx context.Context, tld string, localAddr *net.TCPAddr) (baseURL string, err error) {
+	tld = strings.ToLower(tld)
+
+	wl.rdapBootstrap.m.RLock()
+	loaded := wl.rdapBootstrap.services != nil
+	fresh := loaded && !wl.rdapBootstrap.lastUpdated.Before(time.Now().Add(-wl.config.RootCacheDuration))
+	urls, ok := wl.rdapBootstrap.services[tld]
+	wl.rdapBootstrap.m.RUnlock()
+
+	// Offline only serves a bootstrap file that is already loaded, regardless of its age
+	if !fresh && wl.config.Offline && !loaded {
+		err = fmt.Errorf("%w: RDAP bootstrap for TLD: %s", ErrOffline, tld)
+		return baseURL, err
+	}
+
+	if !fresh && !wl.config.Offline {
+		if err = wl.refreshRDAPBootstrap(ctx, localAddr); err != nil {
+			return baseURL, err
+		}
+		wl.rdapBootstrap.m.RLock()
+		urls, ok = wl.rdapBootstrap.services[tld]
+		wl.rdapBootstrap.m.RUnlock()
+	}
+
+	if !ok || len(urls) == 0 {
+		err = fmt.Errorf("%w: %s", ErrRDAPServerNotFound, tld)
+		return baseURL, err
+	}
+
+	// Prefer https when the registry publishes more than one URL
+	baseURL = urls[0]
+	for _, u := range urls {
+		if strings.HasPrefix(u, "https://") {
+			baseURL = u
+			break
+		}
+	}
+
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+
+	return baseURL, err
+}
+
+// refreshRDAPBootstrap downloads the IANA bootstrap file and replaces the cached services.
+func (wl *WhoisLookup) refreshRDAPBootstrap(ctx context.Context, localAddr *net.TCPAddr) (err error) {
+
+	var body []byte
+	if body, err = wl.httpGet(ctx, wl.config.RDAPBootstrapURL, localAddr); err != nil {
+		err = fmt.Errorf("httpGet() url:%s error:%w", wl.config.RDAPBootstrapURL, err)
+		return err
+	}
+
+	var bootstrap rdapBootstrap
+	if err = json.Unmarshal(body, &bootstrap); err != nil {
+		err = fmt.Errorf("json.Unmarshal() error:%w", err)
+		return err
+	}
+
+	services := make(map[string][]string)
+	for _, service := range bootstrap.Services {
+		if len(service) < 2 {
+			continue
+		}
+		for _, tld := range service[0] {
+			services[strings.ToLower(tld)] = service[1]
+		}
+	}
+
+	wl.rdapBootstrap.m.Lock()
+	wl.rdapBootstrap.services = services
+	wl.rdapBootstrap.lastUpdated = time.Now()
+	wl.rdapBootstrap.m.Unlock()
+
+	return err
+}
+
+// convertRDAPDomain maps an RDAP domain object to WhoisInfo.
+func convertRDAPDomain(d rdapDomain) (info WhoisInfo) {
+
+	name := strings.ToLower(strings.TrimSuffix(d.LDHName, "."))
+	info.Domain = &Domain{
+		ID:          d.Handle,
+		Domain:      name,
+		Punycode:    name,
+		WhoisServer: d.Port43,
+	}
+	if d.UnicodeName != "" {
+		info.Domain.Domain = strings.ToLower(d.UnicodeName)
+	}
+	if i := strings.Index(name, "."); i > 0 {
+		info.Domain.Name = name[:i]
+		info.Domain.Extension = name[i+1:]
+	}
+
+	for _, status := range d.Status {
+		info.Domain.Status = append(info.Domain.Status, rdapStatusToEPP(status))
+	}
+	info.Domain.EPPStatus = parseEPPStatuses(d.Status)
+
+	for _, ns := range d.Nameservers {
+		if ns.LDHName != "" {
+			info.Domain.NameServers = append(info.Domain.NameServers, strings.ToLower(strings.TrimSuffix(ns.LDHName, ".")))
+		}
+	}
+
+	if d.SecureDNS != nil {
+		info.Domain.DNSSec = d.SecureDNS.DelegationSigned
+		for _, ds := range d.SecureDNS.DSData {
+			info.Domain.DSData = append(info.Domain.DSData, DSRecord{
+				KeyTag:     ds.KeyTag,
+				Algorithm:  ds.Algorithm,
+				DigestType: ds.DigestType,
+				Digest:     strings.ToUpper(ds.Digest),
+			})
+		}
+	}
+
+	// The ICANN RDAP profile links the complaint form from a notice
+	for _, notice := range d.Notices {
+		if strings.Contains(strings.ToLower(notice.Title), "inaccuracy complaint") && len(notice.Links) > 0 {
+			info.Domain.InaccuracyComplaintURL = notice.Links[0].Href
+		}
+	}
+
+	for _, event := range d.Events {
+		var t *time.Time
+		if parsed, err := time.Parse(time.RFC3339, event.EventDate); err == nil {
+			t = &parsed
+		}
+		switch event.EventAction {
+		case "registration":
+			info.Domain.CreatedDate, info.Domain.CreatedDateInTime = event.EventDate, t
+		case "last changed":
+			info.Domain.UpdatedDate, info.Domain.UpdatedDateInTime = event.EventDate, t
+		case "expiration":
+			info.Domain.ExpirationDate, info.Domain.ExpirationDateInTime = event.EventDate, t
+		}
+	}
+
+	for _, entity := range d.Entities {
+		contact := convertRDAPEntity(entity)
+		for _, role := range entity.Roles {
+			switch role {
+			case "registrar":
+				info.Registrar = contact
+				info.RegistrarIANAID, info.RegistrarAbuse = rdapRegistrarDetails(entity)
+			case "registrant":
+				info.Registrant = contact
+			case "administrative":
+				info.Administrative = contact
+			case "technical":
+				info.Technical = contact
+			case "billing":
+				info.Billing = contact
+			}
+		}
+	}
+	detectRedactions(&info)
+
+	return info
+}
+
+// rdapStatusToEPP converts RDAP status values ("client transfer prohibited") to
+// the EPP form used by WHOIS ("clientTransferProhibited").
+func rdapStatusToEPP(status string) string {
+	words := strings.Fields(status)
+	if len(words) == 0 {
+		return status
+	}
+	if len(words) == 1 && strings.EqualFold(words[0], "active") {
+		return "ok"
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.ToLower(words[0]))
+	for _, w := range words[1:] {
+		w = strings.ToLower(w)
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	return b.String()
+}
+
+// rdapRegistrarDetails returns the IANA ID and the abuse contact of a registrar entity.
+// The abuse contact is a nested entity with the "abuse" role.
+func rdapRegistrarDetails(registrar rdapEntity) (ianaID string, abuse *AbuseContact) {
+	for _, id := range registrar.PublicIDs {
+		if id.Type == "IANA Registrar ID" {
+			ianaID = id.Identifier
+		}
+	}
+
+	for _, entity := range registrar.Entities {
+		for _, role := range entity.Roles {
+			if role != "abuse" {
+				continue
+			}
+			contact := convertRDAPEntity(entity)
+			if contact.Email != "" || contact.Phone != "" {
+				abuse = &AbuseContact{Email: contact.Email, Phone: contact.Phone}
+			}
+		}
+	}
+
+	return ianaID, abuse
+}
+
+// convertRDAPEntity maps an RDAP entity and its jCard to a Contact.
+func convertRDAPEntity(entity rdapEntity) (c *Contact) {
+	c = &Contact{ID: entity.Handle}
+
+	for _, id := range entity.PublicIDs {
+		if id.Type == "IANA Registrar ID" {
+			c.ID = id.Identifier
+		}
+	}
+
+	for _, link := range entity.Links {
+		if link.Rel == "about" && c.ReferralURL == "" {
+			c.ReferralURL = link.Href
+		}
+	}
+
+	for _, prop := range parseVCard(entity.VCardArray) {
+		switch prop.name {
+		case "fn":
+			c.Name = prop.text()
+		case "org":
+			c.Organization = prop.text()
+		case "email":
+			c.Email = prop.text()
+		case "tel":
+			number := strings.TrimPrefix(prop.text(), "tel:")
+			number, ext, _ := strings.Cut(number, ";ext=")
+			if prop.hasType("fax") {
+				c.Fax, c.FaxExt = number, ext
+			} else if c.Phone == "" {
+				c.Phone, c.PhoneExt = number, ext
+			}
+		case "adr":
+			adr := prop.list()
+			// post office box, extended address, street, locality, region, postal code, country
+			if len(adr) >= 7 {
+				c.Street = strings.TrimSpace(strings.Join(nonEmpty(adr[0], adr[1], adr[2]), ", "))
+				c.City = adr[3]
+				c.Province = adr[4]
+				c.PostalCode = adr[5]
+				c.Country = adr[6]
+			}
+			if cc, ok := prop.params["cc"].(string); ok && cc != "" {
+				c.Country = cc
+			}
+		}
+	}
+
+	return c
+}
+
+// vcardProperty is one jCard property: [name, params, type, value...].
+type vcardProperty struct {
+	name   string
+	params map[string]interface{}
+	values []interface{}
+}
+
+func parseVCard(vcardArray []json.RawMessage) (props []vcardProperty) {
+	if len(vcardArray) < 2 {
+		return props
+	}
+
+	var raw [][]interface{}
+	if err := json.Unmarshal(vcardArray[1], &raw); err != nil {
+		return props
+	}
+
+	for _, p := range raw {
+		if len(p) < 4 {
+			continue
+		}
+		name, _ := p[0].(string)
+		params, _ := p[1].(map[string]interface{})
+		props = append(props, vcardProperty{
+			name:   strings.ToLower(name),
+			params: params,
+			values: p[3:],
+		})
+	}
+
+	return props
+}
+
+func (p vcardProperty) text() string {
+	var parts []string
+	for _, v := range p.values {
+		switch t := v.(type) {
+		case string:
+			parts = append(parts, t)
+		case []interface{}:
+			for _, s := range t {
+				if str, ok := s.(string); ok && str != "" {
+					parts = append(parts, str)
+				}
+			}
+		}
+	}
+	return strings.Join(nonEmpty(parts...), " ")
+}
+
+func (p vcardProperty) list() (out []string) {
+	if len(p.values) == 0 {
+		return out
+	}
+	values, ok := p.values[0].([]interface{})
+	if !ok {
+		return out
+	}
+	for _, v := range values {
+		switch t := v.(type) {
+		case string:
+			out = append(out, t)
+		case []interface{}:
+			var parts []string
+			for _, s := range t {
+				if str, ok := s.(string); ok {
+					parts = append(parts, str)
+				}
+			}
+			out = append(out, strings.Join(nonEmpty(parts...), ", "))
+		default:
+			out = append(out, "")
+		}
+	}
+	return out
+}
+
+func (p vcardProperty) hasType(want string) bool {
+	switch t := p.params["type"].(type) {
+	case string:
+		return strings.EqualFold(t, want)
+	case []interface{}:
+		for _, v := range t {
+			if s, ok := v.(string); ok && strings.EqualFold(s, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func nonEmpty(values ...string) (out []string) {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,252 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testRDAPRegistry = `{
+  "objectClassName": "domain",
+  "handle": "2138514_DOMAIN_COM-VRSN",
+  "ldhName": "EXAMPLE.COM",
+  "status": ["client delete prohibited", "client transfer prohibited"],
+  "nameservers": [{"objectClassName": "nameserver", "ldhName": "A.IANA-SERVERS.NET"}],
+  "secureDNS": {"delegationSigned": true, "dsData": [{"keyTag": 370, "algorithm": 13, "digestType": 2, "digest": "be74359954660069d5c63d200c39f5603827d7dd02b56f120ee9f3a86764247c"}]},
+  "events": [
+    {"eventAction": "registration", "eventDate": "1995-08-14T04:00:00Z"},
+    {"eventAction": "expiration", "eventDate": "2026-08-13T04:00:00Z"}
+  ],
+  "entities": [{
+    "objectClassName": "entity",
+    "roles": ["registrar"],
+    "publicIds": [{"type": "IANA Registrar ID", "identifier": "376"}],
+    "vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "RESERVED-Internet Assigned Numbers Authority"]]],
+    "entities": [{
+      "objectClassName": "entity",
+      "roles": ["abuse"],
+      "vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["tel", {"type": "voice"}, "uri", "tel:+1.3103015800"], ["email", {}, "text", "abuse@iana.org"]]]
+    }]
+  }],
+  "notices": [
+    {"title": "RDDS Inaccuracy Complaint Form", "description": ["URL of the ICANN RDDS Inaccuracy Complaint Form: https://icann.org/wicf"], "links": [{"href": "https://icann.org/wicf", "type": "text/html"}]}
+  ],
+  "links": [
+    {"value": "%[1]s/domain/EXAMPLE.COM", "rel": "self", "href": "%[1]s/domain/EXAMPLE.COM", "type": "application/rdap+json"},
+    {"value": "%[1]s/registrar/domain/EXAMPLE.COM", "rel": "related", "href": "%[1]s/registrar/domain/EXAMPLE.COM", "type": "application/rdap+json"}
+  ],
+  "port43": "whois.verisign-grs.com"
+}`
+
+const testRDAPRegistrar = `{
+  "objectClassName": "domain",
+  "ldhName": "example.com",
+  "entities": [{
+    "objectClassName": "entity",
+    "roles": ["registrant"],
+    "vcardArray": ["vcard", [
+      ["version", {}, "text", "4.0"],
+      ["fn", {}, "text", "Jane Doe"],
+      ["org", {}, "text", "Example Inc."],
+      ["adr", {}, "text", ["", "", "1 Main St", "Springfield", "IL", "62701", "US"]],
+      ["tel", {"type": "voice"}, "uri", "tel:+1.5555551212;ext=12"],
+      ["email", {}, "text", "jane@example.com"]
+    ]]
+  }]
+}`
+
+func newTestRDAPServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dns.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"version":"1.0","services":[[["com","net"],["%s/"]]]}`, srv.URL)
+	})
+	mux.HandleFunc("/domain/example.com", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", rdapContentType)
+		fmt.Fprintf(w, testRDAPRegistry, srv.URL)
+	})
+	mux.HandleFunc("/registrar/domain/EXAMPLE.COM", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", rdapContentType)
+		fmt.Fprint(w, testRDAPRegistrar)
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestGetRDAP(t *testing.T) {
+	srv := newTestRDAPServer(t)
+	whoisLookup := Setup(&Config{RDAPBootstrapURL: srv.URL + "/dns.json"})
+
+	lookup, err := whoisLookup.lookupRDAP(context.Background(), "example.com", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	registry := lookup.registry
+	if registry.Domain.Domain != "example.com" || registry.Domain.Extension != "com" {
+		t.Errorf("unexpected domain: %+v", registry.Domain)
+	}
+	if registry.Domain.WhoisServer != "whois.verisign-grs.com" {
+		t.Errorf("expected port43 to map to WhoisServer, got %v", registry.Domain.WhoisServer)
+	}
+	if len(registry.Domain.Status) != 2 || registry.Domain.Status[1] != "clientTransferProhibited" {
+		t.Errorf("unexpected status: %v", registry.Domain.Status)
+	}
+	if !registry.Domain.DNSSec || registry.Domain.CreatedDateInTime == nil || registry.Domain.ExpirationDateInTime == nil {
+		t.Errorf("unexpected dnssec/dates: %+v", registry.Domain)
+	}
+	if registry.Registrar == nil || registry.Registrar.ID != "376" {
+		t.Errorf("unexpected registrar: %+v", registry.Registrar)
+	}
+	if registry.RegistrarIANAID != "376" {
+		t.Errorf("unexpected registrar IANA ID: %v", registry.RegistrarIANAID)
+	}
+	if registry.RegistrarAbuse == nil || registry.RegistrarAbuse.Email != "abuse@iana.org" || registry.RegistrarAbuse.Phone != "+1.3103015800" {
+		t.Errorf("unexpected registrar abuse contact: %+v", registry.RegistrarAbuse)
+	}
+	if !registry.Domain.EPPStatus.IsLocked() || registry.Domain.EPPStatus.IsOnHold() {
+		t.Errorf("unexpected EPP status: %v", registry.Domain.EPPStatus)
+	}
+	if len(registry.Domain.DSData) != 1 || registry.Domain.DSData[0].KeyTag != 370 || registry.Domain.DSData[0].Digest != "BE74359954660069D5C63D200C39F5603827D7DD02B56F120EE9F3A86764247C" {
+		t.Errorf("unexpected DS data: %+v", registry.Domain.DSData)
+	}
+	if registry.Domain.InaccuracyComplaintURL != "https://icann.org/wicf" {
+		t.Errorf("unexpected inaccuracy complaint URL: %v", registry.Domain.InaccuracyComplaintURL)
+	}
+
+	info, raw, err := whoisLookup.GetRDAP(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if raw != testRDAPRegistrar {
+		t.Errorf("expected registrar raw response, got %v", raw)
+	}
+
+	registrant := info.Registrant
+	if registrant == nil {
+		t.Fatal("expected registrant from registrar RDAP")
+	}
+	if registrant.Name != "Jane Doe" || registrant.Organization != "Example Inc." || registrant.Email != "jane@example.com" {
+		t.Errorf("unexpected registrant: %+v", registrant)
+	}
+	if registrant.Street != "1 Main St" || registrant.City != "Springfield" || registrant.Country != "US" {
+		t.Errorf("unexpected registrant address: %+v", registrant)
+	}
+	if registrant.Phone != "+1.5555551212" || registrant.PhoneExt != "12" {
+		t.Errorf("unexpected registrant phone: %+v", registrant)
+	}
+}
+
+func TestGetRDAP_UnknownTLD(t *testing.T) {
+	srv := newTestRDAPServer(t)
+	whoisLookup := Setup(&Config{RDAPBootstrapURL: srv.URL + "/dns.json"})
+
+	_, _, err := whoisLookup.GetRDAP(context.Background(), "example.invalid")
+	if !errors.Is(err, ErrRDAPServerNotFound) {
+		t.Fatalf("expected ErrRDAPServerNotFound, got %v", err)
+	}
+}
+
+func TestGetWhoisWithLocalAddr_ProtocolStrategy(t *testing.T) {
+	srv := newTestRDAPServer(t)
+
+	tests := []struct {
+		name     string
+		strategy ProtocolStrategy
+		protocol Protocol
+		wantErr  bool
+	}{
+		{name: "rdap only", strategy: StrategyRDAPOnly, protocol: ProtocolRDAP},
+		{name: "rdap then whois", strategy: StrategyRDAPThenWhois, protocol: ProtocolRDAP},
+		{name: "whois then rdap", strategy: StrategyWhoisThenRDAP, protocol: ProtocolRDAP},
+		{name: "whois only", strategy: StrategyWhoisOnly, protocol: ProtocolWhois, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			whoisLookup := Setup(&Config{
+				RDAPBootstrapURL: srv.URL + "/dns.json",
+				// Nothing listens here so the WHOIS path always fails
+				WhoisTLDServer:   "127.0.0.1:1",
+				DisableBootstrap: true,
+				ProtocolStrategy: tt.strategy,
+				RetryPolicy:      RetryPolicy{MaxAttempts: 1},
+			})
+
+			result, err := whoisLookup.GetWhoisWithLocalAddr(context.Background(), "example.com", nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Protocol != tt.protocol {
+				t.Errorf("expected protocol %v, got %v", tt.protocol, result.Protocol)
+			}
+			if tt.wantErr {
+				return
+			}
+			if result.TLD != "com" || result.RegistryRDAPRaw == "" || result.RegistrarRDAPRaw != testRDAPRegistrar {
+				t.Errorf("unexpected result: %+v", result)
+			}
+			if result.RegistrarWhois == nil || result.RegistrarWhois.Registrant == nil {
+				t.Errorf("expected registrar info, got %+v", result.RegistrarWhois)
+			}
+		})
+	}
+}
+
+func TestLookupRDAP_NotFound(t *testing.T) {
+	srv := newTestRDAPServer(t)
+	whoisLookup := Setup(&Config{RDAPBootstrapURL: srv.URL + "/dns.json"})
+
+	_, err := whoisLookup.lookupRDAP(context.Background(), "unregistered.com", nil)
+	if !errors.Is(err, ErrDomainNotFound) || !errors.Is(err, ErrRDAPStatus) {
+		t.Fatalf("expected ErrDomainNotFound, got %v", err)
+	}
+}
+
+func TestGetRDAP_RegistrarFailure(t *testing.T) {
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dns.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"version":"1.0","services":[[["com"],["%s/"]]]}`, srv.URL)
+	})
+	mux.HandleFunc("/domain/example.com", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", rdapContentType)
+		fmt.Fprintf(w, testRDAPRegistry, srv.URL)
+	})
+	mux.HandleFunc("/registrar/domain/EXAMPLE.COM", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	whoisLookup := Setup(&Config{RDAPBootstrapURL: srv.URL + "/dns.json"})
+
+	// The registry record is returned when only the registrar fails
+	info, raw, err := whoisLookup.GetRDAP(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Domain == nil || info.Domain.WhoisServer != "whois.verisign-grs.com" || raw != fmt.Sprintf(testRDAPRegistry, srv.URL) {
+		t.Errorf("expected the registry record, got %+v", info.Domain)
+	}
+
+	// The failure is recorded on the registrar hop
+	whoisLookup = Setup(&Config{RDAPBootstrapURL: srv.URL + "/dns.json", ProtocolStrategy: StrategyRDAPOnly})
+	result, err := whoisLookup.GetWhoisWithLocalAddr(context.Background(), "example.com", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RegistryWhois == nil || result.RegistrarWhois != nil {
+		t.Errorf("expected only the registry record, got %+v, %+v", result.RegistryWhois, result.RegistrarWhois)
+	}
+	if len(result.Hops) != 2 || result.Hops[0].Error != "" || result.Hops[1].Error == "" {
+		t.Errorf("expected the error on the registrar hop, got %+v", result.Hops)
+	}
+}
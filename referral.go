@@ -0,0 +1,106 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Hop is one server queried during a lookup, in the order the referrals were followed.
+type Hop struct {
+	Server  string        `json:"server"`
+	Raw     string        `json:"raw"`
+	Info    *WhoisInfo    `json:"info,omitempty"`
+	Latency time.Duration `json:"latency"`
+	// Encoding is the character set the response was decoded from
+	Encoding string `json:"encoding,omitempty"`
+	// RawBytes is the undecoded response, kept when Config.KeepRawBytes is set
+	RawBytes []byte `json:"raw_bytes,omitempty"`
+	// Truncated is set when the response was cut down to the configured limits
+	Truncated bool `json:"truncated,omitempty"`
+	// Error is set when the hop failed. Failures past the first WHOIS registrar, and of the
+	// RDAP registrar, end the walk but do not fail the lookup.
+	Error string `json:"error,omitempty"`
+	// Attempts is the number of tries the query took, see Config.RetryPolicy
+	Attempts int `json:"attempts,omitempty"`
+}
+
+// walkReferrals queries server and keeps following the WHOIS server each response refers to,
+// up to Config.MaxReferralDepth hops, none when it is negative. visited holds the servers already queried (normally the
+// registry) and is used for loop detection. A failure of the first hop is returned as an error,
+// later failures are recorded on the hop and end the walk.
+func (wl *WhoisLookup) walkReferrals(ctx context.Context, domain, server string, visited map[string]bool, localAddr *net.TCPAddr) (hops []Hop, err error) {
+
+	for depth := 0; depth < wl.config.MaxReferralDepth; depth++ {
+		server = normalizeReferral(server)
+		if server == "" || visited[server] {
+			return hops, nil
+		}
+		visited[server] = true
+
+		hop, err := wl.queryHop(ctx, StageRegistrar, domain, server, localAddr)
+		if err != nil {
+			err = errors.Join(ErrWhoisRegistrar, fmt.Errorf("queryServer() server:%s error:%w", server, err))
+			return referralFailed(hops, hop, err)
+		}
+
+		var info WhoisInfo
+		if info, err = wl.parse(hop.Raw, server, domain); err != nil {
+			err = parseError(ErrParseWhoisRegistrar, err)
+			return referralFailed(hops, hop, err)
+		}
+		hop.Info = &info
+		hops = append(hops, hop)
+
+		if info.Domain == nil {
+			return hops, nil
+		}
+		server = info.Domain.WhoisServer
+	}
+
+	return hops, nil
+}
+
+// hop builds the Hop for a response from server.
+func (resp whoisResponse) hop(server string, latency time.Duration) Hop {
+	return Hop{
+		Server:    server,
+		Raw:       resp.Raw,
+		Latency:   latency,
+		Encoding:  resp.Encoding,
+		RawBytes:  resp.Bytes,
+		Truncated: resp.Truncated,
+	}
+}
+
+// referralFailed records a failed hop. Only a failure of the first hop is returned as an error.
+func referralFailed(hops []Hop, hop Hop, err error) ([]Hop, error) {
+	hop.Error = err.Error()
+	hops = append(hops, hop)
+	if len(hops) == 1 {
+		return hops, err
+	}
+	return hops, nil
+}
+
+// lastSuccessfulHop returns the deepest hop that was parsed successfully.
+func lastSuccessfulHop(hops []Hop) (hop Hop, ok bool) {
+	for i := len(hops) - 1; i >= 0; i-- {
+		if hops[i].Info != nil {
+			return hops[i], true
+		}
+	}
+	return hop, false
+}
+
+// normalizeReferral turns the referral forms found in responses ("whois://Whois.Example.com/",
+// "Whois.Example.com") into a plain lower case host.
+func normalizeReferral(server string) string {
+	server = strings.TrimSpace(server)
+	server = strings.TrimPrefix(server, "whois://")
+	server = strings.TrimSuffix(server, "/")
+	return strings.ToLower(server)
+}
@@ -0,0 +1,157 @@
+package whois
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func testReferralWhois(whoisServer string) string {
+	return fmt.Sprintf(`Domain Name: EXAMPLE.COM
+Registry Domain ID: 2336799_DOMAIN_COM-VRSN
+Registrar WHOIS Server: %s
+Updated Date: 2024-08-14T07:01:34Z
+Creation Date: 1995-08-14T04:00:00Z
+Registrar: RESERVED-Internet Assigned Numbers Authority
+Registrar IANA ID: 376
+Name Server: A.IANA-SERVERS.NET
+DNSSEC: signedDelegation
+`, whoisServer)
+}
+
+func TestWalkReferrals(t *testing.T) {
+	var registrarA, registrarB string
+	registrarA = newTestWhoisServer(t, func(query string) string {
+		return testReferralWhois(registrarB)
+	})
+	registrarB = newTestWhoisServer(t, func(query string) string {
+		// Refers back to registrar A, the walk must stop here
+		return testReferralWhois(registrarA)
+	})
+	registry := newTestWhoisServer(t, func(query string) string {
+		return testReferralWhois(registrarA)
+	})
+
+	whoisLookup := Setup(nil)
+	whoisLookup.setTLDServerToCache("com", registry)
+
+	result, err := whoisLookup.GetWhoisWithLocalAddr(context.Background(), "example.com", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Hops) != 3 {
+		t.Fatalf("expected 3 hops, got %d: %+v", len(result.Hops), result.Hops)
+	}
+	for i, want := range []string{registry, registrarA, registrarB} {
+		if result.Hops[i].Server != want {
+			t.Errorf("hop %d: expected server %v, got %v", i, want, result.Hops[i].Server)
+		}
+		if result.Hops[i].Info == nil || result.Hops[i].Raw == "" || result.Hops[i].Latency <= 0 {
+			t.Errorf("hop %d: incomplete hop %+v", i, result.Hops[i])
+		}
+	}
+	if result.RegistrarWhoisRaw != result.Hops[2].Raw {
+		t.Errorf("expected registrar raw from the deepest hop")
+	}
+}
+
+func TestWalkReferrals_MaxDepth(t *testing.T) {
+	var registrarA, registrarB string
+	registrarA = newTestWhoisServer(t, func(query string) string {
+		return testReferralWhois(registrarB)
+	})
+	registrarB = newTestWhoisServer(t, func(query string) string {
+		return testReferralWhois("127.0.0.1:1")
+	})
+	registry := newTestWhoisServer(t, func(query string) string {
+		return testReferralWhois(registrarA)
+	})
+
+	whoisLookup := Setup(&Config{MaxReferralDepth: 1})
+	whoisLookup.setTLDServerToCache("com", registry)
+
+	result, err := whoisLookup.GetWhoisWithLocalAddr(context.Background(), "example.com", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Hops) != 2 || result.Hops[1].Server != registrarA {
+		t.Fatalf("expected registry and one registrar hop, got %+v", result.Hops)
+	}
+}
+
+func TestWalkReferrals_SelfReferral(t *testing.T) {
+	var registry string
+	registry = newTestWhoisServer(t, func(query string) string {
+		return testReferralWhois(registry)
+	})
+
+	whoisLookup := Setup(nil)
+	whoisLookup.setTLDServerToCache("com", registry)
+
+	result, err := whoisLookup.GetWhoisWithLocalAddr(context.Background(), "example.com", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Hops) != 1 {
+		t.Fatalf("expected the registry to be queried once, got %+v", result.Hops)
+	}
+	if result.RegistrarWhois == nil || result.RegistrarWhoisRaw != result.RegistryWhoisRaw {
+		t.Errorf("expected the registry record as registrar record, got %+v", result.RegistrarWhois)
+	}
+}
+
+func TestWalkReferrals_Disabled(t *testing.T) {
+	var registrarQueries atomic.Int32
+	registrar := newTestWhoisServer(t, func(query string) string {
+		registrarQueries.Add(1)
+		return testReferralWhois("")
+	})
+	registry := newTestWhoisServer(t, func(query string) string {
+		return testReferralWhois(registrar)
+	})
+
+	whoisLookup := Setup(&Config{MaxReferralDepth: -1})
+	whoisLookup.setTLDServerToCache("com", registry)
+
+	result, err := whoisLookup.GetWhoisWithLocalAddr(context.Background(), "example.com", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Hops) != 1 || result.RegistrarWhois != nil || registrarQueries.Load() != 0 {
+		t.Errorf("expected only the registry to be queried, got %+v", result.Hops)
+	}
+}
+
+func TestGetRegistrarWhois_RegistrarFailure(t *testing.T) {
+	// Nothing listens on the registrar
+	registry := newTestWhoisServer(t, func(query string) string {
+		return testReferralWhois("127.0.0.1:1")
+	})
+
+	whoisLookup := Setup(&Config{RetryPolicy: RetryPolicy{MaxAttempts: 1}})
+	whoisLookup.setTLDServerToCache("com", registry)
+
+	info, raw, err := whoisLookup.GetRegistrarWhois(context.Background(), "example.com")
+	if err == nil {
+		t.Fatal("expected the registrar failure")
+	}
+	// The registry record must not be paired with the registrar response
+	if info.Domain != nil || raw != "" {
+		t.Errorf("expected no record and the registrar response, got %+v, %q", info.Domain, raw)
+	}
+}
+
+func TestNormalizeReferral(t *testing.T) {
+	tests := map[string]string{
+		"whois://Whois.Example.com/": "whois.example.com",
+		" whois.example.com ":        "whois.example.com",
+		"":                           "",
+	}
+	for input, want := range tests {
+		if got := normalizeReferral(input); got != want {
+			t.Errorf("normalizeReferral(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
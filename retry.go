@@ -0,0 +1,200 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// ErrorClass groups errors for RetryPolicy.RetryOn.
+type ErrorClass string
+
+const (
+	// ErrorClassNetwork covers refused and reset connections and other transport failures
+	ErrorClassNetwork ErrorClass = "network"
+	// ErrorClassTimeout covers dial and read timeouts
+	ErrorClassTimeout ErrorClass = "timeout"
+	// ErrorClassRateLimited covers ErrRateLimited
+	ErrorClassRateLimited ErrorClass = "rate_limited"
+)
+
+// RetryPolicy controls how failed queries are retried. It is applied separately to the IANA,
+// registry and registrar hops of a lookup, so a retry only repeats the hop that failed.
+type RetryPolicy struct {
+	// MaxAttempts is the number of tries per hop including the first, 1 disables retries
+	MaxAttempts int `json:"max_attempts"`
+	// InitialBackoff is the delay before the first retry
+	InitialBackoff time.Duration `json:"initial_backoff"`
+	// MaxBackoff caps the delay between attempts. A rate limited response asking for a
+	// longer wait than MaxBackoff is not retried.
+	MaxBackoff time.Duration `json:"max_backoff"`
+	// Multiplier grows the delay after each retry
+	Multiplier float64 `json:"multiplier"`
+	// Jitter randomizes each delay by up to this fraction (0.2 is ±20%), 0 disables it
+	Jitter float64 `json:"jitter"`
+	// RetryOn lists the retryable error classes, empty means all of them
+	RetryOn []ErrorClass `json:"retry_on"`
+	// Retryable replaces RetryOn with a custom check when set
+	Retryable func(err error) bool `json:"-"`
+}
+
+// Stage names the part of a lookup an Attempt belongs to.
+type Stage string
+
+const (
+	StageIANA      Stage = "iana"
+	StageRegistry  Stage = "registry"
+	StageRegistrar Stage = "registrar"
+)
+
+// Attempt is one try at querying a server. Error is empty for the attempt that succeeded.
+type Attempt struct {
+	Stage   Stage         `json:"stage"`
+	Server  string        `json:"server"`
+	Attempt int           `json:"attempt"`
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// classifyError returns the ErrorClass of err, or "" for errors that are never retried.
+func classifyError(err error) ErrorClass {
+	if errors.Is(err, ErrRateLimited) {
+		return ErrorClassRateLimited
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ""
+	}
+
+	var netErr net.Error
+	if errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ErrorClassTimeout
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrorClassNetwork
+	}
+
+	return ""
+}
+
+// retryable reports whether err may succeed on another attempt.
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+
+	class := classifyError(err)
+	if class == "" {
+		return false
+	}
+	if len(p.RetryOn) == 0 {
+		return true
+	}
+	for _, c := range p.RetryOn {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay after the given failed attempt, starting at 1.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		delay *= p.Multiplier
+		if delay >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.Jitter > 0 {
+		delay *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	if delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	return time.Duration(delay)
+}
+
+// retry calls query until it succeeds, fails with an error the policy does not retry or
+// runs out of attempts. Every attempt is recorded on the attempt log in ctx, if any.
+func (wl *WhoisLookup) retry(ctx context.Context, stage Stage, server string, query func() error) (attempts int, err error) {
+	policy := wl.config.RetryPolicy
+
+	for attempts = 1; ; attempts++ {
+		start := time.Now()
+		err = query()
+		recordAttempt(ctx, Attempt{Stage: stage, Server: server, Attempt: attempts, Latency: time.Since(start), Error: errorString(err)})
+
+		if err == nil || attempts >= policy.MaxAttempts || !policy.retryable(err) || ctx.Err() != nil {
+			return attempts, err
+		}
+
+		delay := policy.backoff(attempts)
+		var rateErr *RateLimitedError
+		if errors.As(err, &rateErr) && rateErr.RetryAfter > delay {
+			if rateErr.RetryAfter > policy.MaxBackoff {
+				return attempts, err
+			}
+			delay = rateErr.RetryAfter
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return attempts, err
+		}
+	}
+}
+
+// queryHop queries a registry or registrar server for domain with retries and returns the hop.
+// The hop's latency is that of the last attempt.
+func (wl *WhoisLookup) queryHop(ctx context.Context, stage Stage, domain, server string, localAddr *net.TCPAddr) (hop Hop, err error) {
+	var (
+		resp    whoisResponse
+		latency time.Duration
+	)
+	hop.Attempts, err = wl.retry(ctx, stage, server, func() (queryErr error) {
+		start := time.Now()
+		resp, queryErr = wl.queryServer(ctx, queryDomain, domain, server, wl.config.DefaultTimeout, localAddr)
+		latency = time.Since(start)
+		return queryErr
+	})
+
+	attempts := hop.Attempts
+	hop = resp.hop(server, latency)
+	hop.Attempts = attempts
+
+	return hop, err
+}
+
+type attemptLogKey struct{}
+
+// withAttemptLog returns a context that records attempts made under it to log.
+func withAttemptLog(ctx context.Context, log *[]Attempt) context.Context {
+	return context.WithValue(ctx, attemptLogKey{}, log)
+}
+
+func recordAttempt(ctx context.Context, attempt Attempt) {
+	if log, ok := ctx.Value(attemptLogKey{}).(*[]Attempt); ok {
+		*log = append(*log, attempt)
+	}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,95 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestGetWhoisWithLocalAddr_Retry(t *testing.T) {
+	var registryCalls atomic.Int32
+	registrar := newTestWhoisServer(t, func(query string) string {
+		return testReferralWhois("")
+	})
+	registry := newTestWhoisServer(t, func(query string) string {
+		if registryCalls.Add(1) == 1 {
+			return "WHOIS LIMIT EXCEEDED - SEE WWW.EXAMPLE.COM/WHOIS FOR DETAILS\n"
+		}
+		return testReferralWhois(registrar)
+	})
+
+	whoisLookup := Setup(&Config{RetryPolicy: RetryPolicy{InitialBackoff: time.Millisecond}})
+	whoisLookup.setTLDServerToCache("com", registry)
+
+	result, err := whoisLookup.GetWhoisWithLocalAddr(context.Background(), "example.com", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Attempts) != 3 {
+		t.Fatalf("expected 3 attempts, got %+v", result.Attempts)
+	}
+	if a := result.Attempts[0]; a.Stage != StageRegistry || a.Attempt != 1 || a.Error == "" {
+		t.Errorf("unexpected first attempt: %+v", a)
+	}
+	if a := result.Attempts[1]; a.Stage != StageRegistry || a.Attempt != 2 || a.Error != "" {
+		t.Errorf("unexpected second attempt: %+v", a)
+	}
+	if a := result.Attempts[2]; a.Stage != StageRegistrar || a.Server != registrar {
+		t.Errorf("unexpected third attempt: %+v", a)
+	}
+	if result.Hops[0].Attempts != 2 || result.Hops[1].Attempts != 1 {
+		t.Errorf("unexpected hop attempts: %+v", result.Hops)
+	}
+}
+
+func TestRetry_Policy(t *testing.T) {
+	refused := fmt.Errorf("dialer.DialContext() error:%w", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED})
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		err      error
+		attempts int
+	}{
+		{name: "not retryable", policy: RetryPolicy{MaxAttempts: 3}, err: ErrResponseTooLarge, attempts: 1},
+		{name: "network", policy: RetryPolicy{MaxAttempts: 3}, err: refused, attempts: 3},
+		{name: "class not selected", policy: RetryPolicy{MaxAttempts: 3, RetryOn: []ErrorClass{ErrorClassRateLimited}}, err: refused, attempts: 1},
+		{name: "custom", policy: RetryPolicy{MaxAttempts: 4, Retryable: func(err error) bool { return errors.Is(err, ErrResponseTooLarge) }}, err: ErrResponseTooLarge, attempts: 4},
+		{name: "retry after too long", policy: RetryPolicy{MaxAttempts: 3}, err: &RateLimitedError{RetryAfter: time.Hour}, attempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := tt.policy
+			policy.InitialBackoff, policy.MaxBackoff, policy.Multiplier = time.Millisecond, 10*time.Millisecond, 2
+			whoisLookup := Setup(&Config{RetryPolicy: policy})
+
+			attempts, err := whoisLookup.retry(context.Background(), StageRegistry, "whois.example.com", func() error { return tt.err })
+			if attempts != tt.attempts || err != tt.err {
+				t.Errorf("expected %d attempts, got %d error:%v", tt.attempts, attempts, err)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 5: time.Second} {
+		if got := policy.backoff(attempt); got != want {
+			t.Errorf("attempt %d: expected %v, got %v", attempt, want, got)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.backoff(1); got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("jittered backoff out of range: %v", got)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package whois
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrRateLimited is returned (wrapped in a *RateLimitedError) when a server answers with a throttling banner.
+	ErrRateLimited = errors.New("Rate limited by server")
+)
+
+// RateLimitedError reports a throttled query. RetryAfter is zero when the server did not suggest a delay.
+// It matches ErrRateLimited with errors.Is.
+type RateLimitedError struct {
+	Server     string
+	RetryAfter time.Duration
+	Banner     string
+}
+
+func (e *RateLimitedError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s: server:%s retry after:%s", ErrRateLimited, e.Server, e.RetryAfter)
+	}
+	return fmt.Sprintf("%s: server:%s", ErrRateLimited, e.Server)
+}
+
+func (e *RateLimitedError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// rateLimitBanners match throttling responses from any server. Per server banners are
+// added with ServerProfile.RateLimitBanners.
+var rateLimitBanners = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)whois limit exceeded`),
+	regexp.MustCompile(`(?i)connection limit exceeded`),
+	regexp.MustCompile(`(?i)query rate of .* exceeded`),
+	regexp.MustCompile(`(?i)(rate|query|request) limit (exceeded|reached)`),
+	regexp.MustCompile(`(?i)quota exceeded`),
+	regexp.MustCompile(`(?i)exceeded the (maximum|allowed) (number of )?(queries|requests|connections)`),
+	regexp.MustCompile(`(?i)too many (queries|requests|connections)`),
+	regexp.MustCompile(`(?i)access control limit (exceeded|reached)`),
+}
+
+var retryAfterRegexp = regexp.MustCompile(`(?i)(?:try again|retry|wait)\D{0,20}?(\d+)\s*(second|sec|minute|min|hour)`)
+
+// rateLimited returns a *RateLimitedError if raw is a throttling banner from whoisServer.
+// Only the first lines are checked so that legal notices further down a real record can not match.
+func rateLimited(whoisServer, raw string, banners []string) error {
+
+	head := headLines(raw, 30)
+
+	var banner string
+	for _, re := range rateLimitBanners {
+		if banner = re.FindString(head); banner != "" {
+			break
+		}
+	}
+	if banner == "" {
+		lower := strings.ToLower(head)
+		for _, b := range banners {
+			if b != "" && strings.Contains(lower, strings.ToLower(b)) {
+				banner = b
+				break
+			}
+		}
+	}
+	if banner == "" {
+		return nil
+	}
+
+	return &RateLimitedError{
+		Server:     whoisServer,
+		RetryAfter: parseRetryAfter(head),
+		Banner:     banner,
+	}
+}
+
+// parseRetryAfter finds a suggested delay such as "try again in 10 minutes".
+func parseRetryAfter(text string) time.Duration {
+	m := retryAfterRegexp.FindStringSubmatch(text)
+	if m == nil {
+		return 0
+	}
+
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0
+	}
+
+	switch strings.ToLower(m[2]) {
+	case "hour":
+		return time.Duration(n) * time.Hour
+	case "minute", "min":
+		return time.Duration(n) * time.Minute
+	}
+	return time.Duration(n) * time.Second
+}
+
+// rdapRateLimited returns a *RateLimitedError for an HTTP 429 response, honouring Retry-After.
+func rdapRateLimited(url string, resp *http.Response) error {
+	err := &RateLimitedError{Server: url, Banner: resp.Status}
+
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, convErr := strconv.Atoi(retryAfter); convErr == nil {
+			err.RetryAfter = time.Duration(seconds) * time.Second
+		} else if t, parseErr := http.ParseTime(retryAfter); parseErr == nil {
+			err.RetryAfter = time.Until(t)
+		}
+	}
+
+	return err
+}
+
+// headLines returns the first n lines of raw.
+func headLines(raw string, n int) string {
+	if lines := strings.SplitN(raw, "\n", n+1); len(lines) > n {
+		return strings.Join(lines[:n], "\n")
+	}
+	return raw
+}
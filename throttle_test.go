@@ -0,0 +1,76 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimited(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		banners    []string
+		limited    bool
+		retryAfter time.Duration
+	}{
+		{name: "record", raw: "Domain Name: EXAMPLE.COM\nRegistrar: Example Registrar\n"},
+		{name: "generic", raw: "WHOIS LIMIT EXCEEDED - SEE WWW.PIR.ORG/WHOIS FOR DETAILS\n", limited: true},
+		{name: "connection limit", raw: "%% Your connection limit exceeded. Please slow down and try again in 10 minutes.\n", limited: true, retryAfter: 10 * time.Minute},
+		{name: "query rate", raw: "Query rate of 100 queries per hour exceeded, please wait 30 seconds\n", limited: true, retryAfter: 30 * time.Second},
+		{name: "profile banner", raw: "Access denied, come back tomorrow\n", banners: []string{"ACCESS DENIED"}, limited: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := rateLimited("whois.example.com", tt.raw, tt.banners)
+			if !tt.limited {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var rateErr *RateLimitedError
+			if !errors.As(err, &rateErr) || !errors.Is(err, ErrRateLimited) {
+				t.Fatalf("expected RateLimitedError, got %v", err)
+			}
+			if rateErr.Server != "whois.example.com" || rateErr.RetryAfter != tt.retryAfter {
+				t.Errorf("unexpected error fields: %+v", rateErr)
+			}
+		})
+	}
+}
+
+func TestQueryServer_RateLimited(t *testing.T) {
+	server := newTestWhoisServer(t, func(query string) string {
+		return "Your connection limit exceeded. Please slow down and try again later.\n"
+	})
+
+	whoisLookup := Setup(&Config{})
+	resp, err := whoisLookup.queryServer(context.Background(), queryDomain, "example.com", server, whoisLookup.config.DefaultTimeout, nil)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+	if resp.Raw == "" {
+		t.Errorf("expected banner to be returned as raw response")
+	}
+}
+
+func TestHTTPGet_RateLimited(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	whoisLookup := Setup(&Config{})
+	_, err := whoisLookup.httpGet(context.Background(), ts.URL, nil)
+	var rateErr *RateLimitedError
+	if !errors.As(err, &rateErr) || rateErr.RetryAfter != 2*time.Minute {
+		t.Fatalf("expected RateLimitedError with 2m retry, got %v", err)
+	}
+}
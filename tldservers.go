@@ -0,0 +1,70 @@
+package whois
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//go:generate go run ./internal/gentldservers -o tldservers.txt
+
+var (
+	// ErrOffline is returned in offline mode when an answer would need a query to IANA.
+	ErrOffline = errors.New("IANA query not allowed in offline mode")
+)
+
+// tldServersSnapshot is the bundled IANA root zone WHOIS server table, see Config.DisableBootstrap.
+//
+//go:embed tldservers.txt
+var tldServersSnapshot string
+
+// parseTLDServers parses a TLD server table: "# generated: <RFC 3339 time>" and one
+// "tld server" line per TLD, other "#" lines are comments.
+func parseTLDServers(data string) (generated time.Time, servers map[string]string, err error) {
+	servers = make(map[string]string)
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if value, ok := strings.CutPrefix(line, "# generated:"); ok {
+			if generated, err = time.Parse(time.RFC3339, strings.TrimSpace(value)); err != nil {
+				err = fmt.Errorf("line %d: time.Parse() error:%w", i+1, err)
+				return generated, nil, err
+			}
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			err = fmt.Errorf("line %d: expected \"tld server\", got %q", i+1, line)
+			return generated, nil, err
+		}
+		servers[strings.ToLower(fields[0])] = fields[1]
+	}
+
+	if len(servers) > 0 && generated.IsZero() {
+		err = errors.New("missing \"# generated:\" line")
+		return generated, nil, err
+	}
+	return generated, servers, err
+}
+
+// bootstrapTLDServers seeds the TLD server cache from a snapshot, normally tldServersSnapshot.
+// The entries date from the snapshot and stay fresh for Config.BootstrapMaxAge.
+func (wl *WhoisLookup) bootstrapTLDServers(snapshot string) {
+	generated, servers, err := parseTLDServers(snapshot)
+	if err != nil {
+		// The snapshot is checked by the tests, an unreadable one seeds nothing
+		return
+	}
+
+	wl.m.Lock()
+	defer wl.m.Unlock()
+
+	for tld, server := range servers {
+		wl.rootWhoisServers[tld] = rootTLDCache{Host: server, LastUpdated: generated, Bootstrap: true}
+	}
+}
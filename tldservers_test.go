@@ -0,0 +1,137 @@
+package whois
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// setupWithSnapshot returns a WhoisLookup seeded from a snapshot generated now instead of the
+// bundled one.
+func setupWithSnapshot(config *Config) *WhoisLookup {
+	config.DisableBootstrap = true
+	whoisLookup := Setup(config)
+	whoisLookup.bootstrapTLDServers("# generated: " + time.Now().UTC().Format(time.RFC3339) + "\ncom whois.verisign-grs.com\n")
+	return whoisLookup
+}
+
+func TestTLDServersSnapshot(t *testing.T) {
+	if _, _, err := parseTLDServers(tldServersSnapshot); err != nil {
+		t.Fatalf("bundled snapshot does not parse: %v", err)
+	}
+
+	generated, servers, err := parseTLDServers("# comment\n# generated: 2024-01-02T03:04:05Z\nCOM whois.verisign-grs.com\n\n")
+	if err != nil || !generated.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) || servers["com"] != "whois.verisign-grs.com" {
+		t.Errorf("unexpected snapshot: %v, %v, %v", generated, servers, err)
+	}
+
+	_, bundled, _ := parseTLDServers(tldServersSnapshot)
+	if len(bundled) == 0 {
+		t.Skip("bundled snapshot is empty, run go generate")
+	}
+	if bundled["com"] != "whois.verisign-grs.com" {
+		t.Errorf("expected com -> whois.verisign-grs.com in the bundled snapshot, got %q", bundled["com"])
+	}
+
+	if _, _, err = parseTLDServers("# generated: 2024-01-02T03:04:05Z\ncom\n"); err == nil {
+		t.Error("expected an error for a line without a server")
+	}
+	if _, _, err = parseTLDServers("com whois.verisign-grs.com\n"); err == nil {
+		t.Error("expected an error for a snapshot without a generation time")
+	}
+}
+
+func TestBootstrapTLDServers(t *testing.T) {
+	var ianaQueries atomic.Int32
+	iana := newTestWhoisServer(t, func(query string) string {
+		ianaQueries.Add(1)
+		return "whois: whois.iana-answer.test\n"
+	})
+	ctx := context.Background()
+
+	// Seeded entries are used without asking IANA, unknown TLDs are queried
+	whoisLookup := setupWithSnapshot(&Config{WhoisTLDServer: iana})
+	if server, err := whoisLookup.GetTLDWhoisServer(ctx, "com"); err != nil || server != "whois.verisign-grs.com" {
+		t.Errorf("expected seeded server for com, got %v, %v", server, err)
+	}
+	if server, err := whoisLookup.GetTLDWhoisServer(ctx, "unknown"); err != nil || server != "whois.iana-answer.test" {
+		t.Errorf("expected IANA server for an unknown TLD, got %v, %v", server, err)
+	}
+	if ianaQueries.Load() != 1 {
+		t.Errorf("expected one IANA query, got %d", ianaQueries.Load())
+	}
+
+	// A snapshot older than BootstrapMaxAge is refreshed from IANA
+	whoisLookup = setupWithSnapshot(&Config{WhoisTLDServer: iana, BootstrapMaxAge: time.Nanosecond})
+	if server, err := whoisLookup.GetTLDWhoisServer(ctx, "com"); err != nil || server != "whois.iana-answer.test" {
+		t.Errorf("expected IANA server for a stale entry, got %v, %v", server, err)
+	}
+
+	// A stale entry is still used when IANA can not be reached
+	whoisLookup = setupWithSnapshot(&Config{WhoisTLDServer: "127.0.0.1:1", BootstrapMaxAge: time.Nanosecond, RetryPolicy: RetryPolicy{MaxAttempts: 1}})
+	if server, err := whoisLookup.GetTLDWhoisServer(ctx, "com"); err != nil || server != "whois.verisign-grs.com" {
+		t.Errorf("expected stale seeded server, got %v, %v", server, err)
+	}
+}
+
+func TestOffline(t *testing.T) {
+	var ianaQueries atomic.Int32
+	iana := newTestWhoisServer(t, func(query string) string {
+		ianaQueries.Add(1)
+		return "whois: whois.iana-answer.test\n"
+	})
+	ctx := context.Background()
+
+	whoisLookup := setupWithSnapshot(&Config{WhoisTLDServer: iana, Offline: true, BootstrapMaxAge: time.Nanosecond})
+	if server, err := whoisLookup.GetTLDWhoisServer(ctx, "com"); err != nil || server != "whois.verisign-grs.com" {
+		t.Errorf("expected seeded server regardless of age, got %v, %v", server, err)
+	}
+
+	_, err := whoisLookup.GetTLDWhoisServer(ctx, "unknown")
+	if !errors.Is(err, ErrOffline) || !errors.Is(err, ErrWhoisServerNotFound) {
+		t.Errorf("expected ErrOffline and ErrWhoisServerNotFound, got %v", err)
+	}
+	if _, _, err = whoisLookup.GetIPWhois(ctx, "192.0.2.1"); !errors.Is(err, ErrOffline) {
+		t.Errorf("expected ErrOffline for an IP lookup, got %v", err)
+	}
+	if ianaQueries.Load() != 0 {
+		t.Errorf("expected no IANA queries, got %d", ianaQueries.Load())
+	}
+}
+
+func TestOffline_RDAP(t *testing.T) {
+	rdap := newTestRDAPServer(t)
+	var bootstrapHits atomic.Int32
+	bootstrap := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bootstrapHits.Add(1)
+		fmt.Fprintf(w, `{"version":"1.0","services":[[["com"],["%s/"]]]}`, rdap.URL)
+	}))
+	t.Cleanup(bootstrap.Close)
+	ctx := context.Background()
+
+	whoisLookup := Setup(&Config{RDAPBootstrapURL: bootstrap.URL, RootCacheDuration: time.Nanosecond, Offline: true})
+	if _, _, err := whoisLookup.GetRDAP(ctx, "example.com"); !errors.Is(err, ErrOffline) {
+		t.Errorf("expected ErrOffline without a loaded bootstrap, got %v", err)
+	}
+	if bootstrapHits.Load() != 0 {
+		t.Fatalf("expected no bootstrap download, got %d", bootstrapHits.Load())
+	}
+
+	// A bootstrap loaded before going offline is served regardless of its age
+	whoisLookup.config.Offline = false
+	if _, _, err := whoisLookup.GetRDAP(ctx, "example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	whoisLookup.config.Offline = true
+	if info, _, err := whoisLookup.GetRDAP(ctx, "example.com"); err != nil || info.Domain.Domain != "example.com" {
+		t.Errorf("expected the stale bootstrap to be served, got %v", err)
+	}
+	if bootstrapHits.Load() != 1 {
+		t.Errorf("expected a single bootstrap download, got %d", bootstrapHits.Load())
+	}
+}
@@ -2,10 +2,13 @@ package whois
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -16,6 +19,8 @@ import (
 var (
 	// ErrWhoisServerNotFound is returned when the WHOIS server for a TLD is not found.
 	ErrWhoisServerNotFound = fmt.Errorf("WHOIS server not found for TLD")
+	// ErrResponseTooLarge is returned when a WHOIS response exceeds Config.MaxResponseBytes or Config.MaxLineLength.
+	ErrResponseTooLarge = errors.New("WHOIS response too large")
 )
 
 type WhoisLookup struct {
@@ -24,11 +29,23 @@ type WhoisLookup struct {
 	config           Config
 	localAddr        *net.TCPAddr
 	localAddrRWMutex sync.RWMutex
+	rdapBootstrap    rdapBootstrapCache
+	rdapClient       *http.Client
+	suffixList       suffixList
+	serverProfiles   map[string]ServerProfile
+	limiters         map[string]*hostLimiter
+	limitersMutex    sync.Mutex
+	serverParsers    map[string]Parser
+	tldParsers       map[string]Parser
+	tldServers       map[string][]string
+	serverRewrites   map[string]string
 }
 
 type rootTLDCache struct {
 	Host        string
 	LastUpdated time.Time
+	// Bootstrap marks entries seeded from the bundled snapshot
+	Bootstrap bool
 }
 
 type Config struct {
@@ -36,8 +53,85 @@ type Config struct {
 	DefaultTimeout    time.Duration `json:"default_timeout"`
 	WhoisTLDServer    string        `json:"whois_tld_server"`
 	LocalAddr         *net.TCPAddr  `json:"local_addr"`
+	RDAPBootstrapURL  string        `json:"rdap_bootstrap_url"`
+	// PublicSuffixListURL is downloaded by RefreshPublicSuffixList.
+	PublicSuffixListURL string       `json:"public_suffix_list_url"`
+	HTTPClient          *http.Client `json:"-"`
+	// MaxResponseBytes caps the size of a single WHOIS response, zero or negative means the default.
+	MaxResponseBytes int64 `json:"max_response_bytes"`
+	// MaxLineLength caps the length of a single line in a WHOIS response, zero or negative means
+	// the default.
+	MaxLineLength int `json:"max_line_length"`
+	// TruncateResponses returns the prefix of oversized responses, flagged as truncated,
+	// instead of failing with ErrResponseTooLarge.
+	TruncateResponses bool `json:"truncate_responses"`
+	// RateLimit throttles every WHOIS host that has no entry in RateLimits. The zero value uses
+	// the default, negative QueriesPerSecond and MaxConcurrent disable the limits.
+	RateLimit RateLimit `json:"rate_limit"`
+	// RateLimits overrides RateLimit per WHOIS host.
+	RateLimits map[string]RateLimit `json:"rate_limits"`
+	// KeepRawBytes retains the undecoded response bytes on each Hop.
+	KeepRawBytes bool `json:"keep_raw_bytes"`
+	// ServerProfiles adds or replaces per host query formats, encodings and ports.
+	ServerProfiles map[string]ServerProfile `json:"server_profiles"`
+	// MaxReferralDepth is the maximum number of referrals followed after the registry. A
+	// negative value disables referrals, only the registry is queried.
+	MaxReferralDepth int `json:"max_referral_depth"`
+	// ProtocolStrategy selects WHOIS, RDAP or a fallback order for GetWhoisWithLocalAddr.
+	ProtocolStrategy ProtocolStrategy `json:"protocol_strategy"`
+	// RetryPolicy retries failed IANA, registry and registrar queries.
+	RetryPolicy RetryPolicy `json:"retry_policy"`
+	// Parser replaces DefaultParser for every response without a more specific parser.
+	Parser Parser `json:"-"`
+	// ServerParsers selects a parser per WHOIS host, they win over TLDParsers.
+	ServerParsers map[string]Parser `json:"-"`
+	// TLDParsers selects a parser per TLD or public suffix ("uk", "co.uk"), the longest match wins.
+	TLDParsers map[string]Parser `json:"-"`
+	// Cache stores GetWhoisWithLocalAddr results, nil disables caching. See WithCacheMode.
+	Cache Cache `json:"-"`
+	// CacheTTL is how long a result is cached.
+	CacheTTL time.Duration `json:"cache_ttl"`
+	// NegativeCacheTTL is how long a domain not found is cached, a negative value disables it.
+	NegativeCacheTTL time.Duration `json:"negative_cache_ttl"`
+	// TLDServerCache keeps TLD WHOIS servers for RootCacheDuration beyond the in memory
+	// cache, for example a FileCache shared across restarts.
+	TLDServerCache TLDServerCache `json:"-"`
+	// DisableBootstrap starts with an empty TLD server cache instead of the bundled IANA snapshot.
+	DisableBootstrap bool `json:"disable_bootstrap"`
+	// BootstrapMaxAge is how long after it was generated the bundled snapshot is trusted,
+	// older entries are refreshed from IANA.
+	BootstrapMaxAge time.Duration `json:"bootstrap_max_age"`
+	// Offline never queries IANA: TLD servers and the RDAP bootstrap come from the caches
+	// regardless of their age and IP and ASN lookups fail with ErrOffline.
+	Offline bool `json:"offline"`
+	// TLDServers sets the WHOIS servers of a TLD or public suffix ("uk", "co.uk"), instead of
+	// asking IANA. A server may carry a port ("whois.example.net:4343"). The servers are tried
+	// in order, the next one is queried when a server can not be reached or fails to answer.
+	TLDServers map[string][]string `json:"tld_servers"`
+	// ServerRewrites sends the queries for a WHOIS host, or host:port, to another one
+	// ("whois.example.com" -> "mirror.internal:4343"). It applies to every server queried,
+	// including IANA and the registrar referred to by the registry.
+	ServerRewrites map[string]string `json:"server_rewrites"`
 }
 
+// Protocol is the lookup protocol that produced a Result.
+type Protocol string
+
+const (
+	ProtocolWhois Protocol = "whois"
+	ProtocolRDAP  Protocol = "rdap"
+)
+
+// ProtocolStrategy controls which protocols GetWhoisWithLocalAddr uses and in which order.
+type ProtocolStrategy string
+
+const (
+	StrategyWhoisOnly     ProtocolStrategy = "whois"
+	StrategyRDAPOnly      ProtocolStrategy = "rdap"
+	StrategyRDAPThenWhois ProtocolStrategy = "rdap_whois"
+	StrategyWhoisThenRDAP ProtocolStrategy = "whois_rdap"
+)
+
 type WhoisInfo struct {
 	Domain         *Domain  `json:"domain,omitempty"`
 	Registrar      *Contact `json:"registrar,omitempty"`
@@ -45,6 +139,10 @@ type WhoisInfo struct {
 	Administrative *Contact `json:"administrative,omitempty"`
 	Technical      *Contact `json:"technical,omitempty"`
 	Billing        *Contact `json:"billing,omitempty"`
+	// RegistrarIANAID is the registrar's IANA ID, gTLD registrars only
+	RegistrarIANAID string `json:"registrar_iana_id,omitempty"`
+	// RegistrarAbuse is the registrar's abuse contact
+	RegistrarAbuse *AbuseContact `json:"registrar_abuse,omitempty"`
 }
 
 type Domain struct {
@@ -63,6 +161,12 @@ type Domain struct {
 	UpdatedDateInTime    *time.Time `json:"updated_date_in_time,omitempty"`
 	ExpirationDate       string     `json:"expiration_date,omitempty"`
 	ExpirationDateInTime *time.Time `json:"expiration_date_in_time,omitempty"`
+	// EPPStatus holds the recognized EPP codes of Status
+	EPPStatus EPPStatuses `json:"epp_status,omitempty"`
+	// DSData are the DNSSEC delegation signer records
+	DSData []DSRecord `json:"ds_data,omitempty"`
+	// InaccuracyComplaintURL is the ICANN Whois Inaccuracy Complaint Form
+	InaccuracyComplaintURL string `json:"inaccuracy_complaint_url,omitempty"`
 }
 
 type Contact struct {
@@ -80,17 +184,56 @@ type Contact struct {
 	FaxExt       string `json:"fax_ext,omitempty"`
 	Email        string `json:"email,omitempty"`
 	ReferralURL  string `json:"referral_url,omitempty"`
+	// Redacted is set when any field is redacted or the contact is a privacy service
+	Redacted bool `json:"redacted,omitempty"`
+	// PrivacyService is the privacy or proxy service named in the contact, if known
+	PrivacyService string `json:"privacy_service,omitempty"`
+	// RedactedFields are the fields holding a redaction notice instead of data
+	RedactedFields ContactFields `json:"redacted_fields,omitempty"`
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		RootCacheDuration: 1 * time.Hour,
-		DefaultTimeout:    15 * time.Second,
-		WhoisTLDServer:    "whois.iana.org:43",
+		RootCacheDuration:   1 * time.Hour,
+		DefaultTimeout:      15 * time.Second,
+		WhoisTLDServer:      "whois.iana.org:43",
+		RDAPBootstrapURL:    "https://data.iana.org/rdap/dns.json",
+		PublicSuffixListURL: "https://publicsuffix.org/list/public_suffix_list.dat",
+		ProtocolStrategy:    StrategyWhoisOnly,
+		MaxReferralDepth:    3,
+		MaxResponseBytes:    2 << 20,
+		MaxLineLength:       256 << 10,
+		RateLimit: RateLimit{
+			QueriesPerSecond: 2,
+			Burst:            5,
+			MaxConcurrent:    4,
+		},
+		RetryPolicy: RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: 250 * time.Millisecond,
+			MaxBackoff:     5 * time.Second,
+			Multiplier:     2,
+			Jitter:         0.2,
+		},
+		CacheTTL:         1 * time.Hour,
+		NegativeCacheTTL: 5 * time.Minute,
+		BootstrapMaxAge:  180 * 24 * time.Hour,
 	}
 }
 
+// Setup returns a WhoisLookup for config, nil means DefaultConfig. Zero values are replaced by
+// their defaults. Entries of config failing Validate are left out, use New to get the error.
 func Setup(config *Config) (whoisLookup *WhoisLookup) {
+	var err error
+	if whoisLookup, err = New(config); err != nil {
+		whoisLookup, _ = New(config.withoutInvalid())
+	}
+	return whoisLookup
+}
+
+// New returns a WhoisLookup for config like Setup, or an error wrapping ErrInvalidConfig when
+// config fails Validate.
+func New(config *Config) (whoisLookup *WhoisLookup, err error) {
 
 	defaultConfig := DefaultConfig()
 	if config == nil {
@@ -105,6 +248,53 @@ func Setup(config *Config) (whoisLookup *WhoisLookup) {
 		if config.WhoisTLDServer == "" {
 			config.WhoisTLDServer = defaultConfig.WhoisTLDServer
 		}
+		if config.RDAPBootstrapURL == "" {
+			config.RDAPBootstrapURL = defaultConfig.RDAPBootstrapURL
+		}
+		if config.PublicSuffixListURL == "" {
+			config.PublicSuffixListURL = defaultConfig.PublicSuffixListURL
+		}
+		if config.MaxResponseBytes <= 0 {
+			config.MaxResponseBytes = defaultConfig.MaxResponseBytes
+		}
+		if config.MaxLineLength <= 0 {
+			config.MaxLineLength = defaultConfig.MaxLineLength
+		}
+		if config.MaxReferralDepth == 0 {
+			config.MaxReferralDepth = defaultConfig.MaxReferralDepth
+		}
+		if config.ProtocolStrategy == "" {
+			config.ProtocolStrategy = defaultConfig.ProtocolStrategy
+		}
+		if config.RateLimit == (RateLimit{}) {
+			config.RateLimit = defaultConfig.RateLimit
+		}
+		if config.RetryPolicy.MaxAttempts == 0 {
+			config.RetryPolicy.MaxAttempts = defaultConfig.RetryPolicy.MaxAttempts
+		}
+		if config.RetryPolicy.InitialBackoff == 0 {
+			config.RetryPolicy.InitialBackoff = defaultConfig.RetryPolicy.InitialBackoff
+		}
+		if config.RetryPolicy.MaxBackoff == 0 {
+			config.RetryPolicy.MaxBackoff = defaultConfig.RetryPolicy.MaxBackoff
+		}
+		if config.RetryPolicy.Multiplier == 0 {
+			config.RetryPolicy.Multiplier = defaultConfig.RetryPolicy.Multiplier
+		}
+		if config.CacheTTL == 0 {
+			config.CacheTTL = defaultConfig.CacheTTL
+		}
+		if config.NegativeCacheTTL == 0 {
+			config.NegativeCacheTTL = defaultConfig.NegativeCacheTTL
+		}
+		if config.BootstrapMaxAge == 0 {
+			config.BootstrapMaxAge = defaultConfig.BootstrapMaxAge
+		}
+	}
+
+	if err = config.Validate(); err != nil {
+		err = fmt.Errorf("config.Validate() error:%w", err)
+		return nil, err
 	}
 
 	localAddr := &net.TCPAddr{}
@@ -112,11 +302,26 @@ func Setup(config *Config) (whoisLookup *WhoisLookup) {
 		localAddr = config.LocalAddr
 	}
 
-	return &WhoisLookup{
+	whoisLookup = &WhoisLookup{
 		rootWhoisServers: make(map[string]rootTLDCache),
 		config:           *config,
 		localAddr:        localAddr,
+		serverProfiles:   mergeServerProfiles(config.ServerProfiles),
+		limiters:         make(map[string]*hostLimiter),
+		serverParsers:    normalizeParsers(config.ServerParsers, serverHost),
+		tldParsers:       normalizeParsers(config.TLDParsers, normalizeSuffix),
+		tldServers:       make(map[string][]string, len(config.TLDServers)),
+		serverRewrites:   normalizeRewrites(config.ServerRewrites),
 	}
+	for suffix, servers := range config.TLDServers {
+		whoisLookup.tldServers[normalizeSuffix(suffix)] = servers
+	}
+	whoisLookup.rdapClient = newHTTPClient(config.DefaultTimeout, whoisLookup.GetLocalAddr)
+	if !config.DisableBootstrap {
+		whoisLookup.bootstrapTLDServers(tldServersSnapshot)
+	}
+
+	return whoisLookup, err
 }
 
 // GetLocalAddr get current localAddr
@@ -159,28 +364,31 @@ func (wl *WhoisLookup) GetRegistryWhois(ctx context.Context, domain string) (who
 // GetRegistryWhoisWithLocalAddr returns the WHOIS information for the specified domain from the registry.
 func (wl *WhoisLookup) GetRegistryWhoisWithLocalAddr(ctx context.Context, domain string, localAddr *net.TCPAddr) (whoisInfo WhoisInfo, whoisRaw string, err error) {
 
-	pieces := strings.Split(domain, ".")
-	if len(pieces) < 2 {
-		err = fmt.Errorf("invalid domain name: %s", domain)
+	var parts domainParts
+	if parts, err = wl.splitDomain(domain); err != nil {
 		return whoisInfo, whoisRaw, err
 	}
-	tld := pieces[len(pieces)-1]
+	domain = parts.RegistrableDomain
 
 	// Get TLD whois server
 	var whoisServer string
-	if whoisServer, err = wl.getWhoisServerForTLD(ctx, tld, nil); err != nil {
+	if whoisServer, err = wl.getWhoisServerForSuffix(ctx, parts.ETLD, localAddr); err != nil {
 		err = fmt.Errorf("getTLDWhoisServer() error:%w", err)
 		return whoisInfo, whoisRaw, err
 	}
 
 	// Query TLD whois server
-	if whoisRaw, err = wl.queryWhois(ctx, domain, whoisServer, wl.config.DefaultTimeout, nil); err != nil {
+	whoisServer, err = wl.queryRegistry(ctx, parts.ETLD, whoisServer, func(server string) (err error) {
+		whoisRaw, err = wl.queryWhois(ctx, domain, server, wl.config.DefaultTimeout, localAddr)
+		return err
+	})
+	if err != nil {
 		err = fmt.Errorf("queryWhois() error:%w", err)
 		return whoisInfo, whoisRaw, err
 	}
 
 	// Parse raw whois data to WhoisInfo
-	if whoisInfo, err = wrapParser(whoisRaw); err != nil {
+	if whoisInfo, err = wl.parse(whoisRaw, whoisServer, domain); err != nil {
 		err = fmt.Errorf("parse error:%w", err)
 		return whoisInfo, whoisRaw, err
 	}
@@ -199,11 +407,37 @@ func (wl *WhoisLookup) GetRegistrarWhois(ctx context.Context, domain string) (wh
 type Result struct {
 	Domain              string     `json:"domain"`
 	TLD                 string     `json:"tld"`
+	DomainASCII         string     `json:"domain_ascii"`
+	DomainUnicode       string     `json:"domain_unicode"`
+	ETLD                string     `json:"etld"`
+	RegistrableDomain   string     `json:"registrable_domain"`
+	Protocol            Protocol   `json:"protocol"`
 	RegistryWhois       *WhoisInfo `json:"registry_whois"`
 	RegistryWhoisRaw    string     `json:"registry_whois_raw"`
+	RegistryRDAPRaw     string     `json:"registry_rdap_raw,omitempty"`
 	RegistrarWhois      *WhoisInfo `json:"registrar_whois"`
 	RegistrarWhoisRaw   string     `json:"registrar_whois_raw"`
+	RegistrarRDAPRaw    string     `json:"registrar_rdap_raw,omitempty"`
 	RegistryWhoisServer string     `json:"registry_whois_server"`
+	RegistryRDAPURL     string     `json:"registry_rdap_url,omitempty"`
+	RegistrarRDAPURL    string     `json:"registrar_rdap_url,omitempty"`
+	// Truncated is set when any hop's response was cut down to the configured limits.
+	Truncated bool `json:"truncated,omitempty"`
+	// Hops lists every server queried for the domain in order, registry first.
+	Hops []Hop `json:"hops,omitempty"`
+	// Attempts lists every query attempt in order, including retries and their errors.
+	Attempts []Attempt `json:"attempts,omitempty"`
+	// FromCache is set when the result was served from Config.Cache.
+	FromCache bool `json:"from_cache,omitempty"`
+}
+
+// setDomainParts records how the input domain was normalized and split.
+func (result *Result) setDomainParts(parts domainParts) {
+	result.TLD = parts.TLD
+	result.DomainASCII = parts.ASCII
+	result.DomainUnicode = parts.Unicode
+	result.ETLD = parts.ETLD
+	result.RegistrableDomain = parts.RegistrableDomain
 }
 
 var (
@@ -215,8 +449,43 @@ var (
 
 	ErrRegistryMissingWhoisServer = errors.New("Registry whois response missing whois server")
 	ErrRegistryMissingDomain      = errors.New("Registry whois response missing domain")
+
+	// ErrDomainNotFound is returned when the server has no record for the domain, usually because it is unregistered.
+	ErrDomainNotFound = errors.New("Domain not found")
+	// ErrReservedDomain is returned when the registry reserves the domain.
+	ErrReservedDomain = errors.New("Domain is reserved by the registry")
+	// ErrPremiumDomain is returned when the domain is only available at a premium price.
+	ErrPremiumDomain = errors.New("Domain is available at premium price")
+	// ErrBlockedDomain is returned when the domain is blocked by a brand protection service.
+	ErrBlockedDomain = errors.New("Domain is blocked due to brand protection")
 )
 
+// domainStateErrors maps the parser's domain state errors to ours.
+var domainStateErrors = map[error]error{
+	whoisparser.ErrNotFoundDomain: ErrDomainNotFound,
+	whoisparser.ErrReservedDomain: ErrReservedDomain,
+	whoisparser.ErrPremiumDomain:  ErrPremiumDomain,
+	whoisparser.ErrBlockedDomain:  ErrBlockedDomain,
+}
+
+// isDomainState reports whether err says what state the domain is in rather than that a query or parse failed.
+func isDomainState(err error) bool {
+	for _, stateErr := range domainStateErrors {
+		if errors.Is(err, stateErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseError wraps a Parser error with kind, unless the server answered with the domain's state.
+func parseError(kind, err error) error {
+	if isDomainState(err) {
+		return err
+	}
+	return errors.Join(kind, fmt.Errorf("parse error:%w", err))
+}
+
 type LookupConfig struct {
 	Timeout   time.Duration
 	LocalAddr *net.TCPAddr
@@ -226,103 +495,193 @@ type LookupConfig struct {
 // If the TLD WHOIS response contains a domain WHOIS server, the domain WHOIS server is queried.
 // Registrar look ups typically contain more detailed information than registry look ups.
 // Registrar look ups require one extra step to query the domain WHOIS server and will take longer.
+// Config.ProtocolStrategy controls whether WHOIS, RDAP or both (with fallback) are used;
+// Result.Protocol records which one produced the result.
+// When Config.Cache is set results are served from and stored in it, see WithCacheMode.
 func (wl *WhoisLookup) GetWhoisWithLocalAddr(ctx context.Context, domain string, localAddr *net.TCPAddr) (result Result, err error) {
 
-	result.Domain = domain
-
-	pieces := strings.Split(domain, ".")
-	if len(pieces) < 2 {
-		err = fmt.Errorf("invalid domain name: %s", domain)
+	var cached bool
+	if result, cached, err = wl.cachedLookup(ctx, domain); cached {
 		return result, err
 	}
-	result.TLD = pieces[len(pieces)-1]
+
+	result, err = wl.lookup(ctx, domain, localAddr)
+	wl.cacheLookup(ctx, domain, result, err)
+
+	return result, err
+}
+
+// lookup queries the servers for domain using the protocols of Config.ProtocolStrategy.
+func (wl *WhoisLookup) lookup(ctx context.Context, domain string, localAddr *net.TCPAddr) (result Result, err error) {
+
+	var protocols []Protocol
+	switch wl.config.ProtocolStrategy {
+	case StrategyRDAPOnly:
+		protocols = []Protocol{ProtocolRDAP}
+	case StrategyRDAPThenWhois:
+		protocols = []Protocol{ProtocolRDAP, ProtocolWhois}
+	case StrategyWhoisThenRDAP:
+		protocols = []Protocol{ProtocolWhois, ProtocolRDAP}
+	default:
+		protocols = []Protocol{ProtocolWhois}
+	}
+
+	var (
+		firstResult Result
+		errs        []error
+	)
+	for i, protocol := range protocols {
+		result = Result{Domain: domain, Protocol: protocol}
+
+		switch protocol {
+		case ProtocolRDAP:
+			err = wl.lookupRDAPResult(ctx, &result, localAddr)
+		default:
+			err = wl.lookupWhoisResult(ctx, &result, localAddr)
+		}
+		for _, hop := range result.Hops {
+			result.Truncated = result.Truncated || hop.Truncated
+		}
+
+		if err == nil {
+			return result, err
+		}
+		if i == 0 {
+			firstResult = result
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", protocol, err))
+	}
+
+	// Every protocol failed, return the preferred protocol's partial result
+	if len(errs) > 1 {
+		return firstResult, errors.Join(errs...)
+	}
+
+	return result, err
+}
+
+// lookupWhoisResult fills result using the port 43 WHOIS registry and registrar hops.
+func (wl *WhoisLookup) lookupWhoisResult(ctx context.Context, result *Result, localAddr *net.TCPAddr) (err error) {
+
+	var parts domainParts
+	if parts, err = wl.splitDomain(result.Domain); err != nil {
+		return err
+	}
+	result.setDomainParts(parts)
+	domain := parts.RegistrableDomain
+	ctx = withAttemptLog(ctx, &result.Attempts)
 
 	// Get TLD whois server
-	// var whoisServer string
-	if result.RegistryWhoisServer, err = wl.getWhoisServerForTLD(ctx, result.TLD, localAddr); err != nil {
+	if result.RegistryWhoisServer, err = wl.getWhoisServerForSuffix(ctx, parts.ETLD, localAddr); err != nil {
 		err = errors.Join(ErrWhoisTLD, fmt.Errorf("getTLDWhoisServer() error:%w", err))
-		return result, err
+		return err
 	}
 
 	// Query TLD whois server / thin record
-	if result.RegistryWhoisRaw, err = wl.queryWhois(ctx, domain, result.RegistryWhoisServer, wl.config.DefaultTimeout, localAddr); err != nil {
+	var registryHop Hop
+	result.RegistryWhoisServer, err = wl.queryRegistry(ctx, parts.ETLD, result.RegistryWhoisServer, func(server string) (err error) {
+		registryHop, err = wl.queryHop(ctx, StageRegistry, domain, server, localAddr)
+		return err
+	})
+	result.RegistryWhoisRaw = registryHop.Raw
+	if err != nil {
 		err = errors.Join(ErrWhoisRegistry, fmt.Errorf("queryWhois() server:%s error:%w", result.RegistryWhoisServer, err))
-		return result, err
+		registryHop.Error = err.Error()
+		result.Hops = append(result.Hops, registryHop)
+		return err
 	}
 
 	var tmpRegistryWhoisInfo WhoisInfo
 	// Parse raw whois data to WhoisInfo / thin record
-	if tmpRegistryWhoisInfo, err = wrapParser(result.RegistryWhoisRaw); err != nil {
-		err = errors.Join(ErrParseWhoisRegistry, fmt.Errorf("parse error:%w", err))
-		return result, err
+	if tmpRegistryWhoisInfo, err = wl.parse(result.RegistryWhoisRaw, result.RegistryWhoisServer, domain); err != nil {
+		err = parseError(ErrParseWhoisRegistry, err)
+		registryHop.Error = err.Error()
+		result.Hops = append(result.Hops, registryHop)
+		return err
 	}
 	result.RegistryWhois = &tmpRegistryWhoisInfo
+	registryHop.Info = result.RegistryWhois
+	result.Hops = append(result.Hops, registryHop)
 
 	if result.RegistryWhois == nil || result.RegistryWhois.Domain == nil {
 		err = ErrRegistryMissingDomain
-		return result, err
+		return err
 	}
 
-	// If TLD whois response contains domain whois server, query domain whois server
-	if result.RegistryWhois.Domain.WhoisServer != "" {
-		if result.RegistrarWhoisRaw, err = wl.queryWhois(ctx, domain, result.RegistryWhois.Domain.WhoisServer, wl.config.DefaultTimeout, localAddr); err != nil {
-			err = errors.Join(ErrWhoisRegistrar, fmt.Errorf("queryWhois() server:%s error:%w", result.RegistryWhois.Domain.WhoisServer, err))
-			return result, err
-		}
-		var tmpRegistrarWhois WhoisInfo
-		if tmpRegistrarWhois, err = wrapParser(result.RegistrarWhoisRaw); err != nil {
-			err = errors.Join(ErrParseWhoisRegistrar, fmt.Errorf("parse error:%w", err))
-			return result, err
-		}
-		result.RegistrarWhois = &tmpRegistrarWhois
-	} else {
+	// If TLD whois response contains domain whois server, follow the referral chain
+	if result.RegistryWhois.Domain.WhoisServer == "" {
 		err = ErrRegistryMissingWhoisServer
+		return err
 	}
 
-	return result, err
+	visited := map[string]bool{normalizeReferral(result.RegistryWhoisServer): true}
+	var hops []Hop
+	hops, err = wl.walkReferrals(ctx, domain, result.RegistryWhois.Domain.WhoisServer, visited, localAddr)
+	result.Hops = append(result.Hops, hops...)
+	if err != nil {
+		return err
+	}
+
+	// The deepest hop is the most authoritative registrar record
+	if hop, ok := lastSuccessfulHop(hops); ok {
+		result.RegistrarWhois = hop.Info
+		result.RegistrarWhoisRaw = hop.Raw
+	} else if len(hops) == 0 && visited[normalizeReferral(result.RegistryWhois.Domain.WhoisServer)] {
+		// Thick registries refer to themselves, their record is also the registrar record
+		registrarWhois := *result.RegistryWhois
+		result.RegistrarWhois = &registrarWhois
+		result.RegistrarWhoisRaw = result.RegistryWhoisRaw
+	}
+
+	return err
 }
 
 // GetRegistrarWhoisWithLocalAddr returns the WHOIS information for the specified domain from the registrar.
 // If the TLD WHOIS response contains a domain WHOIS server, the domain WHOIS server is queried.
 // Registrar look ups typically contain more detailed information than registry look ups.
 // Registrar look ups require one extra step to query the domain WHOIS server and will take longer.
+// When the registrar fails, whoisInfo is empty and whoisRaw is the registrar response.
 func (wl *WhoisLookup) GetRegistrarWhoisWithLocalAddr(ctx context.Context, domain string, localAddr *net.TCPAddr) (whoisInfo WhoisInfo, whoisRaw string, err error) {
 
-	pieces := strings.Split(domain, ".")
-	if len(pieces) < 2 {
-		err = fmt.Errorf("invalid domain name: %s", domain)
+	var parts domainParts
+	if parts, err = wl.splitDomain(domain); err != nil {
 		return whoisInfo, whoisRaw, err
 	}
-	tld := pieces[len(pieces)-1]
+	domain = parts.RegistrableDomain
 
 	// Get TLD whois server
 	var whoisServer string
-	if whoisServer, err = wl.getWhoisServerForTLD(ctx, tld, nil); err != nil {
+	if whoisServer, err = wl.getWhoisServerForSuffix(ctx, parts.ETLD, localAddr); err != nil {
 		err = fmt.Errorf("getTLDWhoisServer() error:%w", err)
 		return whoisInfo, whoisRaw, err
 	}
 
 	// Query TLD whois server
-	if whoisRaw, err = wl.queryWhois(ctx, domain, whoisServer, wl.config.DefaultTimeout, nil); err != nil {
+	whoisServer, err = wl.queryRegistry(ctx, parts.ETLD, whoisServer, func(server string) (err error) {
+		whoisRaw, err = wl.queryWhois(ctx, domain, server, wl.config.DefaultTimeout, localAddr)
+		return err
+	})
+	if err != nil {
 		err = fmt.Errorf("queryWhois() error:%w", err)
 		return whoisInfo, whoisRaw, err
 	}
 
 	// Parse raw whois data to WhoisInfo
-	if whoisInfo, err = wrapParser(whoisRaw); err != nil {
+	if whoisInfo, err = wl.parse(whoisRaw, whoisServer, domain); err != nil {
 		err = fmt.Errorf("parse error:%w", err)
 		return whoisInfo, whoisRaw, err
 	}
 
-	// If TLD whois response contains domain whois server, query domain whois server
-	if whoisInfo.Domain.WhoisServer != "" {
-		if whoisRaw, err = wl.queryWhois(ctx, domain, whoisInfo.Domain.WhoisServer, wl.config.DefaultTimeout, nil); err != nil {
-			err = fmt.Errorf("queryWhois() error:%w", err)
-			return whoisInfo, whoisRaw, err
+	// If TLD whois response contains domain whois server, follow the referral chain
+	if whoisInfo.Domain != nil && whoisInfo.Domain.WhoisServer != "" {
+		visited := map[string]bool{normalizeReferral(whoisServer): true}
+		var hops []Hop
+		if hops, err = wl.walkReferrals(ctx, domain, whoisInfo.Domain.WhoisServer, visited, localAddr); err != nil {
+			// Return the registrar response alone, the registry record describes another server
+			return WhoisInfo{}, hops[len(hops)-1].Raw, err
 		}
-		if whoisInfo, err = wrapParser(whoisRaw); err != nil {
-			err = fmt.Errorf("parse error:%w", err)
-			return whoisInfo, whoisRaw, err
+		if hop, ok := lastSuccessfulHop(hops); ok {
+			whoisInfo, whoisRaw = *hop.Info, hop.Raw
 		}
 	}
 
@@ -330,11 +689,75 @@ func (wl *WhoisLookup) GetRegistrarWhoisWithLocalAddr(ctx context.Context, domai
 }
 
 // queryWhois queries the specified WHOIS server for the specified domain.
+// The query syntax and port come from the server's profile.
+// Failed queries are retried following Config.RetryPolicy.
 func (wl *WhoisLookup) queryWhois(ctx context.Context, domain, whoisServer string, timeout time.Duration, localAddr *net.TCPAddr) (rawWhois string, err error) {
+	_, err = wl.retry(ctx, StageRegistry, whoisServer, func() (queryErr error) {
+		var resp whoisResponse
+		resp, queryErr = wl.queryServer(ctx, queryDomain, domain, whoisServer, timeout, localAddr)
+		rawWhois = resp.Raw
+		return queryErr
+	})
+	return rawWhois, err
+}
+
+// queryServer formats object using the server's profile, sends it and decodes the response to UTF-8.
+func (wl *WhoisLookup) queryServer(ctx context.Context, kind queryKind, object, whoisServer string, timeout time.Duration, localAddr *net.TCPAddr) (resp whoisResponse, err error) {
+
+	profile := wl.serverProfile(whoisServer)
+
+	var (
+		body      []byte
+		truncated bool
+	)
+	if body, truncated, err = wl.dialWhois(ctx, profile.query(kind, object), wl.whoisAddress(whoisServer), timeout, localAddr); err != nil {
+		return resp, err
+	}
+
+	resp = decodeWhois(body, profile.Encoding)
+	resp.Truncated = truncated
+	if wl.config.KeepRawBytes {
+		resp.Bytes = body
+	}
+
+	// Throttling banners are not records, stop them before they reach the parser
+	err = rateLimited(whoisServer, resp.Raw, profile.RateLimitBanners)
+
+	return resp, err
+}
+
+// queryWhoisAddr sends query to the WHOIS server at address (host:port) and returns the
+// response decoded with an auto-detected character set.
+func (wl *WhoisLookup) queryWhoisAddr(ctx context.Context, query, address string, timeout time.Duration, localAddr *net.TCPAddr) (rawWhois string, err error) {
+
+	var body []byte
+	if body, _, err = wl.dialWhois(ctx, query, address, timeout, localAddr); err != nil {
+		return rawWhois, err
+	}
+
+	rawWhois = decodeWhois(body, "").Raw
+	err = rateLimited(address, rawWhois, wl.serverProfile(address).RateLimitBanners)
+
+	return rawWhois, err
+}
+
+// dialWhois sends query to the WHOIS server at address (host:port) and returns the undecoded response.
+// Responses over Config.MaxResponseBytes or with lines over Config.MaxLineLength fail with
+// ErrResponseTooLarge, or are cut down and flagged as truncated when Config.TruncateResponses is set.
+func (wl *WhoisLookup) dialWhois(ctx context.Context, query, address string, timeout time.Duration, localAddr *net.TCPAddr) (body []byte, truncated bool, err error) {
 
 	if localAddr == nil {
 		localAddr = wl.GetLocalAddr()
 	}
+	address = wl.rewriteAddress(address)
+
+	// Wait for the host's rate limit and connection cap
+	var release func()
+	if release, err = wl.limiter(address).acquire(ctx); err != nil {
+		err = fmt.Errorf("rate limiter server:%s error:%w", address, err)
+		return body, truncated, err
+	}
+	defer release()
 
 	var (
 		dialer = net.Dialer{
@@ -344,93 +767,154 @@ func (wl *WhoisLookup) queryWhois(ctx context.Context, domain, whoisServer strin
 		conn net.Conn
 	)
 
-	if conn, err = dialer.DialContext(ctx, "tcp", whoisServer+":43"); err != nil {
+	if conn, err = dialer.DialContext(ctx, "tcp", address); err != nil {
 		err = fmt.Errorf("dialer.DialContext() error:%w", err)
-		return rawWhois, err
+		return body, truncated, err
 	}
 	defer conn.Close()
 
-	// Send the domain query followed by a newline
-	fmt.Fprintf(conn, "%s\r\n", domain)
+	// Send the query followed by a newline
+	fmt.Fprintf(conn, "%s\r\n", query)
 
-	// Read the response from the server
-	var response strings.Builder
-	scanner := bufio.NewScanner(conn)
-	for scanner.Scan() {
-		response.WriteString(scanner.Text() + "\n")
+	// Read the response from the server, decoding happens once the whole response is in.
+	// One byte past the limit is read to tell a response of exactly the limit from a larger one.
+	if body, err = io.ReadAll(io.LimitReader(conn, wl.config.MaxResponseBytes+1)); err != nil {
+		err = fmt.Errorf("error reading response: %w", err)
+		return body, truncated, err
 	}
 
-	// Check for errors during scanning
-	if err = scanner.Err(); err != nil {
-		err = fmt.Errorf("error reading response: %w", err)
-		return rawWhois, fmt.Errorf("error reading response: %w", err)
+	if int64(len(body)) > wl.config.MaxResponseBytes {
+		body = body[:wl.config.MaxResponseBytes]
+		truncated = true
 	}
 
-	rawWhois = response.String()
+	var longLines bool
+	if body, longLines = truncateLines(body, wl.config.MaxLineLength); longLines {
+		truncated = true
+	}
 
-	return rawWhois, err
+	if truncated && !wl.config.TruncateResponses {
+		err = fmt.Errorf("%w: server:%s max bytes:%d max line length:%d", ErrResponseTooLarge, address, wl.config.MaxResponseBytes, wl.config.MaxLineLength)
+		return body, truncated, err
+	}
+
+	return body, truncated, err
+}
+
+// truncateLines cuts every line longer than maxLength bytes down to maxLength.
+func truncateLines(body []byte, maxLength int) (out []byte, truncated bool) {
+	if maxLength <= 0 {
+		return body, false
+	}
+
+	for rest := body; len(rest) > 0; {
+		line := rest
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			line, rest = rest[:i+1], rest[i+1:]
+		} else {
+			rest = nil
+		}
+
+		if content := bytes.TrimRight(line, "\r\n"); len(content) > maxLength {
+			truncated = true
+			out = append(out, content[:maxLength]...)
+			out = append(out, line[len(content):]...)
+			continue
+		}
+		out = append(out, line...)
+	}
+
+	if !truncated {
+		return body, false
+	}
+	return out, truncated
 }
 
-// getTLDServerFromCache returns the WHOIS server for the specified TLD from the cache.
+// getTLDServerFromCache returns the WHOIS server for the specified TLD from the cache,
+// or an empty string when it is missing or stale.
 func (wl *WhoisLookup) getTLDServerFromCache(tld string) (tldWhoisServer string) {
+	if tldWhoisServer, fresh := wl.cachedTLDServer(tld); fresh {
+		return tldWhoisServer
+	}
+	return ""
+}
+
+// cachedTLDServer returns the cached WHOIS server for the specified TLD and whether it is fresh.
+// Entries from IANA are fresh for RootCacheDuration, those from the bundled snapshot for
+// BootstrapMaxAge after it was generated. Config.TLDServerCache is consulted when the in
+// memory cache has no fresh entry.
+func (wl *WhoisLookup) cachedTLDServer(tld string) (tldWhoisServer string, fresh bool) {
 	wl.m.RLock()
-	defer wl.m.RUnlock()
+	rootCache, ok := wl.rootWhoisServers[tld]
+	wl.m.RUnlock()
 
-	if rootCache, ok := wl.rootWhoisServers[tld]; ok {
-		if rootCache.LastUpdated.Before(time.Now().Add(-wl.config.RootCacheDuration)) {
-			// Cache is stale, return empty string
-			return tldWhoisServer
-		} else {
-			tldWhoisServer = rootCache.Host
-			return tldWhoisServer
+	if ok {
+		maxAge := wl.config.RootCacheDuration
+		if rootCache.Bootstrap {
+			maxAge = wl.config.BootstrapMaxAge
+		}
+		if !rootCache.LastUpdated.Before(time.Now().Add(-maxAge)) {
+			return rootCache.Host, true
+		}
+		tldWhoisServer = rootCache.Host
+	}
+
+	// Cache is missing or stale, try the shared cache and keep its answer in memory
+	if wl.config.TLDServerCache != nil {
+		if server, ok := wl.config.TLDServerCache.GetServer(tld); ok {
+			wl.m.Lock()
+			wl.rootWhoisServers[tld] = rootTLDCache{Host: server, LastUpdated: time.Now()}
+			wl.m.Unlock()
+			return server, true
 		}
 	}
-	return tldWhoisServer
+	return tldWhoisServer, false
 }
 
 func (wl *WhoisLookup) setTLDServerToCache(tld string, whoisServer string) {
 	wl.m.Lock()
-	defer wl.m.Unlock()
-
 	wl.rootWhoisServers[tld] = rootTLDCache{Host: whoisServer, LastUpdated: time.Now()}
+	wl.m.Unlock()
+
+	if wl.config.TLDServerCache != nil {
+		wl.config.TLDServerCache.SetServer(tld, whoisServer, wl.config.RootCacheDuration)
+	}
 }
 
 // getWhoisServerForTLD queries the IANA WHOIS server for the specified TLD
 // and returns the WHOIS server associated with that TLD.
+// Config.TLDServers wins over everything else. IANA is only queried when the cache has no fresh entry. A stale entry is used when IANA
+// can not be reached, and regardless of its age in offline mode.
 func (wl *WhoisLookup) getWhoisServerForTLD(ctx context.Context, tld string, localAddr *net.TCPAddr) (whoisServer string, err error) {
-	whoisServer = wl.getTLDServerFromCache(tld)
-
-	if whoisServer != "" {
+	if whoisServer, ok := wl.overrideServer(tld); ok {
 		return whoisServer, nil
 	}
 
-	if localAddr == nil {
-		localAddr = wl.GetLocalAddr()
+	cachedServer, fresh := wl.cachedTLDServer(tld)
+	if fresh || (wl.config.Offline && cachedServer != "") {
+		return cachedServer, nil
 	}
-
-	// Connect to IANA WHOIS server
-	var (
-		dialer = net.Dialer{
-			Timeout:   wl.config.DefaultTimeout,
-			LocalAddr: localAddr,
-		}
-		conn net.Conn
-	)
-
-	if conn, err = dialer.DialContext(ctx, "tcp", wl.config.WhoisTLDServer); err != nil {
-		err = fmt.Errorf("dialer.DialContext() error:%w", err)
-		return whoisServer, err
+	if wl.config.Offline {
+		err = fmt.Errorf("%w: %w for TLD: %s", ErrOffline, ErrWhoisServerNotFound, tld)
+		return "", err
 	}
-	defer conn.Close()
 
-	// Send the TLD query
-	if _, err = conn.Write([]byte(tld + "\r\n")); err != nil {
-		err = fmt.Errorf("conn.Write() error:%w", err)
+	// Query the IANA WHOIS server
+	var ianaRaw string
+	if _, err = wl.retry(ctx, StageIANA, wl.config.WhoisTLDServer, func() (queryErr error) {
+		ianaRaw, queryErr = wl.queryWhoisAddr(ctx, tld, wl.config.WhoisTLDServer, wl.config.DefaultTimeout, localAddr)
+		return queryErr
+	}); err != nil {
+		if cachedServer != "" {
+			return cachedServer, nil
+		}
+		err = fmt.Errorf("queryWhoisAddr() error:%w", err)
 		return whoisServer, err
 	}
 
 	// Read the response
-	scanner := bufio.NewScanner(conn)
+	var referServer string
+	scanner := bufio.NewScanner(strings.NewReader(ianaRaw))
 	for scanner.Scan() {
 		line := scanner.Text()
 		// Look for the line containing the WHOIS server
@@ -443,11 +927,17 @@ func (wl *WhoisLookup) getWhoisServerForTLD(ctx context.Context, tld string, loc
 				return whoisServer, nil
 			}
 		}
+		// Some objects only carry a "refer:" line, used when no "whois:" line follows
+		if strings.HasPrefix(line, "refer:") && referServer == "" {
+			if parts := strings.Fields(line); len(parts) > 1 {
+				referServer = parts[1]
+			}
+		}
 	}
 
-	if err = scanner.Err(); err != nil {
-		err = fmt.Errorf("error reading WHOIS response: %w", err)
-		return whoisServer, err
+	if referServer != "" {
+		wl.setTLDServerToCache(tld, referServer)
+		return referServer, nil
 	}
 
 	err = ErrWhoisServerNotFound
@@ -456,12 +946,20 @@ func (wl *WhoisLookup) getWhoisServerForTLD(ctx context.Context, tld string, loc
 	return "", err
 }
 
-// wrapParser by wrapping the output of whoisparser.WhoisInfo the underlying parser can be changed without affecting the output
+// wrapParser by wrapping the output of whoisparser.WhoisInfo the underlying parser can be changed without affecting the output.
+// It backs DefaultParser, other parsers are plugged in through Config.Parser, ServerParsers and TLDParsers.
 func wrapParser(whoisRaw string) (info WhoisInfo, err error) {
 
 	var whoisInfoP whoisparser.WhoisInfo
 
 	if whoisInfoP, err = whoisparser.Parse(whoisRaw); err != nil {
+		// Not found, reserved, premium and blocked answers are reported with our own errors
+		for parserErr, stateErr := range domainStateErrors {
+			if errors.Is(err, parserErr) {
+				err = fmt.Errorf("%w: whoisparser.Parse() error:%w", stateErr, err)
+				return info, err
+			}
+		}
 		err = fmt.Errorf("whoisparser.Parse() error:%w", err)
 		return info, err
 	}
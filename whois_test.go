@@ -1,11 +1,43 @@
 package whois
 
 import (
+	"bufio"
 	"context"
+	"errors"
+	"net"
+	"strings"
 	"testing"
 	"time"
 )
 
+// newTestWhoisServer starts a port 43 style server on localhost that answers each
+// query with respond(query) and returns its host:port address.
+func newTestWhoisServer(t *testing.T, respond func(query string) string) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				query, _ := bufio.NewReader(conn).ReadString('\n')
+				conn.Write([]byte(respond(strings.TrimRight(query, "\r\n"))))
+			}(conn)
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
 func TestDefaultConfig(t *testing.T) {
 	config := DefaultConfig()
 
@@ -94,3 +126,95 @@ func TestSetAndGetTLDServerFromCache(t *testing.T) {
 		t.Errorf("expected cached server to be %v, got %v", server, cachedServer)
 	}
 }
+
+func TestSetup_NegativeLimits(t *testing.T) {
+	whoisLookup := Setup(&Config{MaxResponseBytes: -1, MaxLineLength: -1})
+
+	// Negative limits would make every query fail, they fall back to the defaults
+	if whoisLookup.config.MaxResponseBytes != DefaultConfig().MaxResponseBytes || whoisLookup.config.MaxLineLength != DefaultConfig().MaxLineLength {
+		t.Errorf("expected default limits, got %v, %v", whoisLookup.config.MaxResponseBytes, whoisLookup.config.MaxLineLength)
+	}
+}
+
+func TestQueryServer_ResponseLimits(t *testing.T) {
+	longLine := strings.Repeat("x", 100)
+	server := newTestWhoisServer(t, func(query string) string {
+		return "Domain Name: EXAMPLE.COM\n" + longLine + "\n" + strings.Repeat("y\n", 100)
+	})
+
+	tests := []struct {
+		name      string
+		config    Config
+		wantErr   bool
+		wantRaw   string
+		truncated bool
+	}{
+		{name: "within limits", config: Config{}, wantRaw: "Domain Name: EXAMPLE.COM\n" + longLine + "\n" + strings.Repeat("y\n", 100)},
+		{name: "too large", config: Config{MaxResponseBytes: 30}, wantErr: true},
+		{name: "line too long", config: Config{MaxLineLength: 50}, wantErr: true},
+		{name: "truncate size", config: Config{MaxResponseBytes: 30, TruncateResponses: true}, wantRaw: "Domain Name: EXAMPLE.COM\nxxxxx\n", truncated: true},
+		{name: "truncate line", config: Config{MaxLineLength: 50, MaxResponseBytes: 200, TruncateResponses: true}, wantRaw: "Domain Name: EXAMPLE.COM\n" + longLine[:50] + "\n" + strings.Repeat("y\n", 37), truncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			whoisLookup := Setup(&config)
+
+			resp, err := whoisLookup.queryServer(context.Background(), queryDomain, "example.com", server, whoisLookup.config.DefaultTimeout, nil)
+			if tt.wantErr {
+				if !errors.Is(err, ErrResponseTooLarge) {
+					t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Raw != tt.wantRaw || resp.Truncated != tt.truncated {
+				t.Errorf("unexpected response: %q truncated:%v", resp.Raw, resp.Truncated)
+			}
+		})
+	}
+}
+
+func TestDomainStateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr error
+	}{
+		{name: "not found", raw: "No match for \"UNREGISTERED.COM\".\n>>> Last update of whois database: 2024-08-14T07:01:34Z <<<\n", wantErr: ErrDomainNotFound},
+		{name: "reserved", raw: "This name is reserved by the registry.\n", wantErr: ErrReservedDomain},
+		{name: "blocked", raw: "This name subscribes to the DPML Brand Protection service.\n", wantErr: ErrBlockedDomain},
+		{name: "garbage", raw: "%% internal error\n", wantErr: ErrParseWhoisRegistry},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := newTestWhoisServer(t, func(query string) string {
+				return tt.raw
+			})
+			whoisLookup := Setup(nil)
+			whoisLookup.setTLDServerToCache("com", registry)
+
+			_, err := whoisLookup.GetWhoisWithLocalAddr(context.Background(), "unregistered.com", nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetWhoisWithLocalAddr: expected %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr != ErrParseWhoisRegistry && errors.Is(err, ErrParseWhoisRegistry) {
+				t.Errorf("GetWhoisWithLocalAddr: domain state reported as parse failure: %v", err)
+			}
+
+			if tt.wantErr == ErrParseWhoisRegistry {
+				return
+			}
+			if _, _, err = whoisLookup.GetRegistryWhois(context.Background(), "unregistered.com"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetRegistryWhois: expected %v, got %v", tt.wantErr, err)
+			}
+			if _, _, err = whoisLookup.GetRegistrarWhois(context.Background(), "unregistered.com"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetRegistrarWhois: expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}